@@ -0,0 +1,241 @@
+package deviceserver
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	h "github.com/CreatorKit/go-deviceserver-client/hateoas"
+)
+
+// TokenRefreshMargin is how long before it expires that a token is refreshed
+var TokenRefreshMargin = time.Minute
+
+// TokenRefreshTimeout limits a refresh, which carries on even if the request that
+// started it is cancelled as others may be waiting for it
+var TokenRefreshTimeout = 30 * time.Second
+
+type noRefreshKey struct{}
+
+// withoutRefresh marks requests which must not trigger a refresh themselves,
+// i.e. those obtaining a token
+func withoutRefresh(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noRefreshKey{}, true)
+}
+
+func refreshDisabled(ctx context.Context) bool {
+	disabled, _ := ctx.Value(noRefreshKey{}).(bool)
+	return disabled
+}
+
+// tokenFlight is a refresh in progress, shared by every goroutine which needs it
+type tokenFlight struct {
+	done chan struct{}
+	err  error
+}
+
+// authDoer sits between the RESTClient's hateoas client and its HTTPDoer, keeping
+// the token fresh for every request, however it is made. A request which is refused
+// with a 401 regardless is replayed once after re-authenticating.
+type authDoer struct {
+	d    *RESTClient
+	next h.HTTPDoer
+}
+
+// Do implements HTTPDoer
+func (a *authDoer) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if refreshDisabled(ctx) {
+		return a.next.Do(req)
+	}
+
+	old, refreshed, err := a.d.ensureToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if refreshed {
+		// the request was built with the token just replaced
+		req = a.d.reauthorize(req, old)
+	}
+
+	if !a.d.canReauthenticate() {
+		return a.next.Do(req)
+	}
+	err = replayable(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := a.next.Do(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	// the token refused is the one sent, which another goroutine may have replaced already
+	rejected := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	err = a.d.forceRefresh(ctx, rejected)
+	if err != nil {
+		return nil, &AuthError{Err: err}
+	}
+	retry := a.d.reauthorize(req, rejected)
+	if req.GetBody != nil {
+		retry.Body, err = req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+	}
+	resp, err = a.next.Do(retry)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	return nil, &AuthError{Err: asHTTPError(&h.HTTPError{
+		StatusCode: resp.StatusCode,
+		Method:     req.Method,
+		URL:        req.URL.String(),
+		Body:       body,
+	})}
+}
+
+// replayable makes sure the request body can be sent again, buffering it if need be
+func replayable(req *http.Request) error {
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody != nil {
+		return nil
+	}
+	buf, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(buf))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(buf)), nil
+	}
+	return nil
+}
+
+// reauthorize returns a copy of `req` using the current token, if it was using `old`
+func (d *RESTClient) reauthorize(req *http.Request, old string) *http.Request {
+	token, _ := d.Token()
+	if old == "" || token.AccessToken == old || req.Header.Get("Authorization") != "Bearer "+old {
+		return req
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	return req
+}
+
+// canReauthenticate is true once there is a TokenSource, refresh token or AccessKey to obtain a new token with
+func (d *RESTClient) canReauthenticate() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.source != nil || d.credentials != nil || d.token.RefreshToken != ""
+}
+
+// wrapDoer installs an authDoer for `d` in front of `doer`, replacing any installed for
+// the client it was copied from (by Clone, or Create given another client's HATEOAS())
+func wrapDoer(d *RESTClient, doer h.HTTPDoer) h.HTTPDoer {
+	if a, ok := doer.(*authDoer); ok {
+		doer = a.next
+	}
+	if doer == nil {
+		doer = &http.Client{}
+	}
+	return &authDoer{d: d, next: doer}
+}
+
+// ensureToken refreshes the token if it is due to expire within TokenRefreshMargin.
+// It returns the access token in use beforehand, and whether it has been replaced.
+// Failing to refresh is only an error once the token has actually expired.
+func (d *RESTClient) ensureToken(ctx context.Context) (string, bool, error) {
+	d.mu.RLock()
+	old := d.token.AccessToken
+	due := !d.tokenExpires.IsZero() && time.Until(d.tokenExpires) <= TokenRefreshMargin
+	d.mu.RUnlock()
+
+	if !due {
+		return old, false, nil
+	}
+	err := d.refresh(ctx, func() bool {
+		return !d.tokenExpires.IsZero() && time.Until(d.tokenExpires) <= TokenRefreshMargin
+	})
+	if err != nil && ctx.Err() == nil {
+		// the token in hand may well still be good, in which case use it until it isn't
+		d.mu.RLock()
+		valid := d.token.AccessToken == old && time.Now().Before(d.tokenExpires)
+		d.mu.RUnlock()
+		if valid {
+			return old, false, nil
+		}
+	}
+	return old, err == nil, err
+}
+
+// forceRefresh obtains a new token, unless `rejected` has already been replaced
+func (d *RESTClient) forceRefresh(ctx context.Context, rejected string) error {
+	return d.refresh(ctx, func() bool {
+		return d.token.AccessToken == rejected
+	})
+}
+
+// refresh re-authenticates if `needed` (called with d.mu held) says so. Only one
+// refresh is in flight at a time, and everyone who needs it waits for it. The refresh
+// isn't tied to any one caller's ctx, so each gives up only when its own is done.
+func (d *RESTClient) refresh(ctx context.Context, needed func() bool) error {
+	d.mu.Lock()
+	flight := d.refreshing
+	if flight == nil {
+		if !needed() {
+			d.mu.Unlock()
+			return nil
+		}
+		flight = &tokenFlight{done: make(chan struct{})}
+		d.refreshing = flight
+		refreshToken, credentials, source := d.token.RefreshToken, d.credentials, d.source
+		go func() {
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), TokenRefreshTimeout)
+			defer cancel()
+			if source != nil {
+				flight.err = d.useToken(ctx, source)
+			} else {
+				flight.err = d.reauthenticate(ctx, refreshToken, credentials)
+			}
+			d.mu.Lock()
+			d.refreshing = nil
+			d.mu.Unlock()
+			close(flight.done)
+		}()
+	}
+	d.mu.Unlock()
+
+	select {
+	case <-flight.done:
+		return flight.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// reauthenticate tries the refresh token, falling back to the original credentials if that is rejected
+func (d *RESTClient) reauthenticate(ctx context.Context, refreshToken string, credentials *AccessKey) error {
+	var err error
+	if refreshToken != "" {
+		err = d.RefreshAuthContext(ctx, refreshToken)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+	}
+	if credentials != nil {
+		return d.AuthenticateContext(ctx, credentials)
+	}
+	return err
+}
@@ -0,0 +1,275 @@
+package deviceserver
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/CreatorKit/go-deviceserver-client/hateoas"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// tokenServer issues numbered tokens, expiring after `expiresIn` seconds
+type tokenServer struct {
+	*httptest.Server
+
+	mu            sync.Mutex
+	issued        int
+	expiresIn     int
+	rejectRefresh bool
+	failGrants    bool
+	delay         time.Duration
+	revoked       map[string]bool
+	grants        []string
+	auth          []string
+	bodies        []string
+}
+
+func createTokenServer(expiresIn int) *tokenServer {
+	ts := &tokenServer{expiresIn: expiresIn, revoked: map[string]bool{}}
+	ts.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ts.mu.Lock()
+		defer ts.mu.Unlock()
+
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `{"Links":[{"rel":"authenticate","href":"/oauth/token"},{"rel":"clients","href":"/clients"},{"rel":"subscriptions","href":"/subscriptions"}]}`)
+		case "/oauth/token":
+			if delay := ts.delay; delay > 0 {
+				ts.mu.Unlock()
+				time.Sleep(delay)
+				ts.mu.Lock()
+			}
+			grant := r.FormValue("grant_type")
+			ts.grants = append(ts.grants, grant)
+			if ts.failGrants {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			if grant == "refresh_token" && ts.rejectRefresh {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			ts.issued++
+			fmt.Fprintf(w, `{"access_token":"t%d","token_type":"bearer","expires_in":%d,"refresh_token":"r%d"}`, ts.issued, ts.expiresIn, ts.issued)
+		default:
+			auth := r.Header.Get("Authorization")
+			ts.auth = append(ts.auth, auth)
+			if ts.revoked[strings.TrimPrefix(auth, "Bearer ")] {
+				w.WriteHeader(http.StatusUnauthorized)
+				fmt.Fprint(w, `{"ErrorCode":"Unauthorized","ErrorMessage":"token revoked"}`)
+				return
+			}
+			if r.Method == "POST" {
+				body, _ := ioutil.ReadAll(r.Body)
+				ts.bodies = append(ts.bodies, string(body))
+				w.WriteHeader(http.StatusCreated)
+				fmt.Fprint(w, `{"ID":"sub","Links":[{"rel":"self","href":"/subscriptions/sub"}]}`)
+				return
+			}
+			fmt.Fprint(w, `{"Items":[]}`)
+		}
+	}))
+	return ts
+}
+
+func TestTokenRefresh(t *testing.T) {
+	assert := assert.New(t)
+
+	// every token expires within the margin, until told otherwise
+	ts := createTokenServer(30)
+	defer ts.Close()
+	d, err := Create(hateoas.Create(&hateoas.Client{EntryURL: ts.URL}))
+	assert.Nil(err)
+
+	assert.Nil(d.Authenticate(&AccessKey{Key: "key", Secret: "secret"}))
+	ts.expiresIn = 3600
+	_, err = d.GetClients(nil)
+	assert.Nil(err)
+	assert.Equal([]string{"password", "refresh_token"}, ts.grants)
+	assert.Equal([]string{"Bearer t2"}, ts.auth)
+
+	// many goroutines hitting expiry share one refresh
+	ts.expiresIn = 30
+	assert.Nil(d.RefreshAuth("r2"))
+	ts.expiresIn = 3600
+	ts.grants = nil
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := d.GetClients(nil)
+			assert.Nil(err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal([]string{"refresh_token"}, ts.grants)
+	token, _ := d.Token()
+	assert.Equal("t4", token.AccessToken)
+
+	// a rejected refresh token falls back to the original key
+	ts.expiresIn = 30
+	assert.Nil(d.RefreshAuth("r4"))
+	ts.expiresIn = 3600
+	ts.rejectRefresh = true
+	ts.grants = nil
+	_, err = d.Clone().GetClients(nil)
+	assert.Nil(err)
+	assert.Equal([]string{"refresh_token", "password"}, ts.grants)
+}
+
+func TestTokenRefreshCancelled(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := createTokenServer(30)
+	defer ts.Close()
+	d, err := Create(hateoas.Create(&hateoas.Client{EntryURL: ts.URL}))
+	assert.Nil(err)
+	assert.Nil(d.Authenticate(&AccessKey{Key: "key", Secret: "secret"}))
+
+	// the caller which starts the refresh gives up, but the refresh carries on for the rest
+	ts.mu.Lock()
+	ts.expiresIn = 3600
+	ts.delay = 200 * time.Millisecond
+	ts.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	started := make(chan struct{})
+	var first error
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		close(started)
+		_, first = d.GetClientsContext(ctx, nil)
+	}()
+	<-started
+	time.Sleep(10 * time.Millisecond)
+	_, err = d.GetClientsContext(context.Background(), nil)
+	assert.Nil(err)
+	wg.Wait()
+	assert.ErrorIs(first, context.DeadlineExceeded)
+	assert.Equal([]string{"password", "refresh_token"}, ts.grants)
+	token, _ := d.Token()
+	assert.Equal("t2", token.AccessToken)
+}
+
+func TestTokenRefreshFailed(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := createTokenServer(30)
+	defer ts.Close()
+	d, err := Create(hateoas.Create(&hateoas.Client{EntryURL: ts.URL}))
+	assert.Nil(err)
+	assert.Nil(d.Authenticate(&AccessKey{Key: "key", Secret: "secret"}))
+
+	// failing to refresh early carries on with the token while it is still valid
+	ts.failGrants = true
+	_, err = d.GetClients(nil)
+	assert.Nil(err)
+	assert.Contains(ts.grants[1:], "refresh_token")
+	assert.Equal([]string{"Bearer t1"}, ts.auth)
+
+	// but not once it has expired
+	d.SetToken(OAuthToken{AccessToken: "t1", RefreshToken: "r1", Expiry: time.Now().Add(-time.Second)})
+	ts.auth = nil
+	_, err = d.GetClients(nil)
+	assert.True(hateoas.IsStatus(err, http.StatusServiceUnavailable))
+	assert.Empty(ts.auth)
+}
+
+func TestUnauthorizedReplay(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := createTokenServer(3600)
+	defer ts.Close()
+	d, err := Create(hateoas.Create(&hateoas.Client{EntryURL: ts.URL}))
+	assert.Nil(err)
+
+	// without any way to re-authenticate, a 401 is just a 401
+	d.SetBearerToken("psk")
+	ts.revoked["psk"] = true
+	_, err = d.GetClients(nil)
+	assert.True(hateoas.IsUnauthorized(err))
+	assert.False(IsAuthError(err))
+
+	// a revoked token is replaced, and the request (body and all) sent again
+	assert.Nil(d.Authenticate(&AccessKey{Key: "key", Secret: "secret"}))
+	ts.revoked["t1"] = true
+	ts.auth = nil
+	var sub SubscriptionResponse
+	err = d.Subscribe("", &SubscriptionRequest{SubscriptionType: "ClientConnected", URL: "http://127.0.0.1/hook"}, &sub)
+	assert.Nil(err)
+	assert.Equal("sub", sub.ID)
+	assert.Equal([]string{"Bearer t1", "Bearer t2"}, ts.auth)
+	assert.Len(ts.bodies, 1)
+	assert.Contains(ts.bodies[0], `"Url":"http://127.0.0.1/hook"`)
+	assert.Equal([]string{"password", "refresh_token"}, ts.grants)
+
+	// a 401 for a token already replaced by someone else is just replayed with the new one
+	ts.grants = nil
+	ts.auth = nil
+	req, _ := http.NewRequest("GET", ts.URL+"/clients", nil)
+	req.Header.Set("Authorization", "Bearer t1")
+	resp, err := d.HATEOAS().Http.Do(req)
+	assert.Nil(err)
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.Empty(ts.grants)
+	assert.Equal([]string{"Bearer t1", "Bearer t2"}, ts.auth)
+
+	// if the new token is refused too, that is an AuthError
+	ts.revoked["t2"] = true
+	ts.revoked["t3"] = true
+	_, err = d.GetClients(nil)
+	assert.True(IsAuthError(err))
+	assert.True(hateoas.IsUnauthorized(err))
+	var dserr *HTTPError
+	assert.True(errors.As(err, &dserr))
+	assert.Equal("token revoked", dserr.ServerError.ErrorMessage)
+
+	// as is failing to re-authenticate at all
+	ts.revoked["t4"] = true
+	ts.rejectRefresh = true
+	d.mu.Lock()
+	d.credentials = nil
+	d.mu.Unlock()
+	_, err = d.GetClients(nil)
+	assert.True(IsAuthError(err))
+	assert.True(hateoas.IsBadRequest(err))
+}
+
+func TestSharedHATEOASClient(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := createTokenServer(3600)
+	defer ts.Close()
+	hclient := hateoas.Create(&hateoas.Client{EntryURL: ts.URL})
+	doer := hclient.Http
+
+	one, err := Create(hclient)
+	assert.Nil(err)
+	two, err := Create(hclient)
+	assert.Nil(err)
+	assert.Equal(doer, hclient.Http)
+
+	// each keeps its own token, and refreshes it itself
+	assert.Nil(one.Authenticate(&AccessKey{Key: "one", Secret: "secret"}))
+	assert.Nil(two.Authenticate(&AccessKey{Key: "two", Secret: "secret"}))
+	ts.revoked["t1"] = true
+	_, err = one.GetClients(nil)
+	assert.Nil(err)
+	_, err = two.GetClients(nil)
+	assert.Nil(err)
+	assert.Equal([]string{"Bearer t1", "Bearer t3", "Bearer t2"}, ts.auth)
+	token, _ := two.Token()
+	assert.Equal("t2", token.AccessToken)
+	assert.Equal("", hclient.DefaultHeaders["Authorization"])
+}
@@ -6,11 +6,14 @@ import (
 	ds "github.com/CreatorKit/go-deviceserver-client"
 	"github.com/urfave/cli"
 	"github.com/CreatorKit/go-deviceserver-client/hateoas"
+	"github.com/square/go-jose"
 )
 
 var (
 	deviceserverPSK string
 	organisationID  int
+	signingKeyFile  string
+	signingAlg      string
 )
 
 var pskFlag = cli.StringFlag{
@@ -27,6 +30,36 @@ var organisationFlag = cli.IntFlag{
 	Value:       0,
 }
 
+var signingKeyFlag = cli.StringFlag{
+	Name:        "signing-key",
+	EnvVar:      "DEVICESERVER_SIGNING_KEY",
+	Destination: &signingKeyFile,
+	Usage:       "PEM private key file to sign with instead of the PSK",
+}
+
+var signingAlgFlag = cli.StringFlag{
+	Name:        "alg",
+	Destination: &signingAlg,
+	Usage:       "Signing algorithm for --signing-key: RS256, PS256 or ES256",
+	Value:       "RS256",
+}
+
+// adminJWT signs an admin token with the --signing-key if given, otherwise the PSK
+func adminJWT() (string, error) {
+	if signingKeyFile == "" {
+		return ds.TokenFromPSK(deviceserverPSK, organisationID)
+	}
+	path, err := expandHome(signingKeyFile)
+	if err != nil {
+		return "", err
+	}
+	key, err := ds.LoadSigningKeyFile(path)
+	if err != nil {
+		return "", err
+	}
+	return ds.TokenFromKey(jose.SignatureAlgorithm(signingAlg), key, organisationID)
+}
+
 var adminToken = cli.Command{
 	Name:      "admin-token",
 	Hidden:    true,
@@ -35,9 +68,11 @@ var adminToken = cli.Command{
 	Flags: []cli.Flag{
 		organisationFlag,
 		pskFlag,
+		signingKeyFlag,
+		signingAlgFlag,
 	},
 	Action: func(c *cli.Context) error {
-		token, err := ds.TokenFromPSK(deviceserverPSK, organisationID)
+		token, err := adminJWT()
 		if err != nil {
 			return err
 		}
@@ -54,6 +89,8 @@ var createOrg = cli.Command{
 	Flags: []cli.Flag{
 		organisationFlag,
 		pskFlag,
+		signingKeyFlag,
+		signingAlgFlag,
 	},
 	Action: func(c *cli.Context) error {
 		keyName := c.Args().Get(0)
@@ -65,7 +102,10 @@ var createOrg = cli.Command{
 		}
 		defer d.Close()
 
-		token, _ := ds.TokenFromPSK(deviceserverPSK, organisationID)
+		token, err := adminJWT()
+		if err != nil {
+			return err
+		}
 		d.SetBearerToken(token)
 
 		key, err := d.CreateAccessKey(keyName)
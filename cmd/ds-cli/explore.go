@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	ds "github.com/CreatorKit/go-deviceserver-client"
+	"github.com/CreatorKit/go-deviceserver-client/hateoas"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+)
+
+var explore = cli.Command{
+	Name:      "explore",
+	Aliases:   []string{"x"},
+	Usage:     "Crawls the deviceserver API and prints the graph of links available to your key",
+	ArgsUsage: " ",
+	Flags: []cli.Flag{
+		cli.IntFlag{
+			Name:  "depth, d",
+			Usage: "How many links away from the entry point to explore",
+			Value: 3,
+		},
+		cli.IntFlag{
+			Name:  "samples, s",
+			Usage: "How many members of each collection to explore",
+			Value: 1,
+		},
+		cli.StringFlag{
+			Name:  "format, f",
+			Usage: "Output format, json or dot",
+			Value: "json",
+		},
+		cli.BoolFlag{
+			Name:  "anonymous, a",
+			Usage: "Explore without authenticating",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		format := c.String("format")
+		if format != "json" && format != "dot" {
+			return errors.Errorf("unknown format %q", format)
+		}
+
+		d, err := ds.Create(hateoas.Create(&hateoas.Client{
+			EntryURL: deviceserverURL,
+		}))
+		if err != nil {
+			return err
+		}
+		defer d.Close()
+
+		if !c.Bool("anonymous") {
+			credentials, err := ReadCredentials()
+			if err != nil {
+				return err
+			}
+
+			err = Authenticate(d, credentials)
+			if err != nil {
+				return err
+			}
+		}
+
+		crawler := hateoas.CreateCrawler(d.HATEOAS())
+		crawler.MaxDepth = c.Int("depth")
+		crawler.SampleItems = c.Int("samples")
+		graph, err := crawler.Crawl(context.Background())
+		if err != nil {
+			return err
+		}
+
+		if format == "dot" {
+			return graph.WriteDOT(os.Stdout)
+		}
+		return graph.WriteJSON(os.Stdout)
+	},
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 
@@ -34,7 +35,7 @@ var createKey = cli.Command{
 			return err
 		}
 
-		err = d.Authenticate(credentials)
+		err = Authenticate(d, credentials)
 		if err != nil {
 			return err
 		}
@@ -74,26 +75,18 @@ var listKeys = cli.Command{
 			return err
 		}
 
-		err = d.Authenticate(credentials)
+		err = Authenticate(d, credentials)
 		if err != nil {
 			return err
 		}
 
-		var previous *ds.AccessKeys = nil
 		count := 0
-		for {
-			keys, err := d.GetAccessKeys(previous)
+		for key, err := range d.AllAccessKeys(context.Background()) {
 			if err != nil {
 				return err
 			}
-			if keys == nil {
-				break
-			}
-			for _, key := range keys.Items {
-				fmt.Printf("[%d] '%s' = %s\n  %s\n\n", count, key.Name, key.Key, key.Links.Self())
-				count++
-			}
-			previous = keys
+			fmt.Printf("[%d] '%s' = %s\n  %s\n\n", count, key.Name, key.Key, key.Links.Self())
+			count++
 		}
 
 		return nil
@@ -139,7 +132,7 @@ var deleteKey = cli.Command{
 			return err
 		}
 
-		err = d.Authenticate(credentials)
+		err = Authenticate(d, credentials)
 		if err != nil {
 			return err
 		}
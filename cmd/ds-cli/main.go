@@ -2,8 +2,10 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/urfave/cli"
@@ -13,6 +15,7 @@ import (
 var (
 	deviceserverURL string
 	credentialsFile string
+	tokenCacheFile  string
 	keyName         string
 )
 
@@ -22,10 +25,11 @@ var keyNameFlag = cli.StringFlag{
 }
 
 func ReadCredentials() (*ds.AccessKey, error) {
-	if credentialsFile[:2] == "~/" {
-		credentialsFile = strings.Replace(credentialsFile, "~", os.Getenv("HOME"), 1)
+	path, err := expandHome(credentialsFile)
+	if err != nil {
+		return nil, err
 	}
-	credFile, err := os.Open(credentialsFile)
+	credFile, err := os.Open(path)
 	if err != nil {
 		return nil, err
 	}
@@ -45,10 +49,11 @@ func ReadCredentials() (*ds.AccessKey, error) {
 }
 
 func WriteCredentials(key *ds.AccessKey) error {
-	if credentialsFile[:2] == "~/" {
-		credentialsFile = strings.Replace(credentialsFile, "~", os.Getenv("HOME"), 1)
+	path, err := expandHome(credentialsFile)
+	if err != nil {
+		return err
 	}
-	credFile, err := os.Create(credentialsFile)
+	credFile, err := os.Create(path)
 	if err != nil {
 		return err
 	}
@@ -61,6 +66,46 @@ func WriteCredentials(key *ds.AccessKey) error {
 	return err
 }
 
+// expandHome replaces a leading "~/" in `path` with the user's home directory
+func expandHome(path string) (string, error) {
+	if !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, path[2:]), nil
+}
+
+// Authenticate reuses the token cached for `credentials` if there is one, refreshing
+// it as required, otherwise authenticates with them. New tokens are cached either way.
+func Authenticate(d *ds.RESTClient, credentials *ds.AccessKey) error {
+	if tokenCacheFile == "" {
+		return d.Authenticate(credentials)
+	}
+
+	path, err := expandHome(tokenCacheFile)
+	if err != nil {
+		return err
+	}
+	cache := ds.CreateFileTokenCache(path)
+	d.OnTokenRefreshed(func(token ds.OAuthToken) {
+		err := cache.Store(deviceserverURL, credentials.Key, token)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to cache token: %s\n", err.Error())
+		}
+	})
+
+	token, err := cache.Load(deviceserverURL, credentials.Key)
+	if err != nil || token == nil {
+		return d.Authenticate(credentials)
+	}
+	d.SetCredentials(credentials)
+	d.SetToken(*token)
+	return nil
+}
+
 func main() {
 	app := cli.NewApp()
 	app.Name = "ds-cli"
@@ -79,6 +124,13 @@ func main() {
 			Value:       "~/.ds-cli",
 			Destination: &credentialsFile,
 		},
+		cli.StringFlag{
+			Name:        "token-cache, t",
+			EnvVar:      "TOKEN_CACHE_FILE",
+			Value:       "~/.ds-cli-tokens",
+			Usage:       "Where to cache access tokens between commands, empty to disable",
+			Destination: &tokenCacheFile,
+		},
 	}
 	app.Commands = []cli.Command{
 		// keys
@@ -86,6 +138,8 @@ func main() {
 		deleteKey,
 		listKeys,
 
+		explore,
+
 		// admin stuff - hidden
 		adminToken,
 		createOrg,
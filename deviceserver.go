@@ -2,10 +2,10 @@
 package deviceserver
 
 import (
-	"bytes"
-	"encoding/json"
-	"fmt"
+	"context"
+	"iter"
 	"net/url"
+	"sync"
 	"time"
 
 	h "github.com/CreatorKit/go-deviceserver-client/hateoas"
@@ -15,18 +15,35 @@ import (
 var (
 	// ErrorInvalidKeyName can be sent in response to CreateAccessKey
 	ErrorInvalidKeyName = "Invalid key name"
+
+	// ErrorAuthFailed prefixes an AuthError
+	ErrorAuthFailed = "re-authentication failed"
 )
 
-// Client is the main object for interacting with the deviceserver
+// Client is the main object for interacting with the deviceserver.
+// It is safe for concurrent use, see also Clone.
 type RESTClient struct {
-	hclient      *h.Client
+	hclient *h.Client
+
+	// mu guards the token state
+	mu           sync.RWMutex
 	token        OAuthToken
 	tokenExpires time.Time
+	credentials  *AccessKey
+	source       TokenSource
+	refreshing   *tokenFlight
+	onRefreshed  func(OAuthToken)
 }
 
 // Create constructs a deviceserver client from a provided hateoas client.
 // If you want logging/caching etc, you should set those options during
-// hateoas client initialisation
+// hateoas client initialisation.
+//
+// The client works on a copy of `hclient` (see hateoas.Client.Clone), whose Http
+// is wrapped so that, once authenticated, the token is refreshed shortly before it
+// expires (see TokenRefreshMargin), using the refresh_token or failing that the
+// AccessKey passed to Authenticate. `hclient` itself is left as it was, so may be
+// shared by several RESTClients.
 func Create(hclient *h.Client) (*RESTClient, error) {
 	if hclient == nil ||
 		hclient.EntryURL == "" {
@@ -34,8 +51,9 @@ func Create(hclient *h.Client) (*RESTClient, error) {
 	}
 
 	d := RESTClient{
-		hclient: hclient,
+		hclient: hclient.Clone(),
 	}
+	d.hclient.Http = wrapDoer(&d, d.hclient.Http)
 
 	return &d, nil
 }
@@ -45,19 +63,107 @@ func (d *RESTClient) Close() {
 
 }
 
+// Clone returns a copy of the client, with its own headers and token state,
+// e.g. for a goroutine that needs to authenticate with different credentials
+func (d *RESTClient) Clone() *RESTClient {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	clone := &RESTClient{
+		hclient:      d.hclient.Clone(),
+		token:        d.token,
+		tokenExpires: d.tokenExpires,
+		credentials:  d.credentials,
+		source:       d.source,
+		onRefreshed:  d.onRefreshed,
+	}
+	clone.hclient.Http = wrapDoer(clone, clone.hclient.Http)
+	return clone
+}
+
 // SetBearerToken sets the Authorization header on the underlying hateoas client
 func (d *RESTClient) SetBearerToken(token string) {
 	if token != "" {
-		d.hclient.DefaultHeaders["Authorization"] = "Bearer " + token
+		d.hclient.SetDefaultHeader("Authorization", "Bearer "+token)
 	} else {
-		delete(d.hclient.DefaultHeaders, "Authorization")
+		d.hclient.DelDefaultHeader("Authorization")
+	}
+}
+
+// Token returns the token obtained by the last Authenticate/RefreshAuth, along
+// with when it expires
+func (d *RESTClient) Token() (OAuthToken, time.Time) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.token, d.tokenExpires
+}
+
+// SetToken starts using a token obtained elsewhere, e.g. from a FileTokenCache. Once it
+// is due to expire it is refreshed as usual, falling back to any SetCredentials. As the
+// token isn't new, the OnTokenRefreshed callback isn't called.
+func (d *RESTClient) SetToken(token OAuthToken) {
+	d.storeToken(token)
+}
+
+// SetCredentials sets the AccessKey to re-authenticate with should the refresh_token
+// be rejected, as if it had been passed to Authenticate
+func (d *RESTClient) SetCredentials(credentials *AccessKey) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.credentials = &AccessKey{Key: credentials.Key, Secret: credentials.Secret}
+	d.source = nil
+}
+
+// OnTokenRefreshed sets a callback for every new token obtained, whether by
+// Authenticate, RefreshAuth, a TokenSource or automatically, e.g. so that a
+// daemon can persist the rotated refresh_token
+func (d *RESTClient) OnTokenRefreshed(callback func(OAuthToken)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.onRefreshed = callback
+}
+
+// setToken records a token the client has just obtained and starts using it, then
+// tells the OnTokenRefreshed callback
+func (d *RESTClient) setToken(token OAuthToken) {
+	token = d.storeToken(token)
+
+	d.mu.RLock()
+	callback := d.onRefreshed
+	d.mu.RUnlock()
+
+	if callback != nil {
+		callback(token)
+	}
+}
+
+// storeToken records `token` and starts using it, returning it with Expiry filled in
+func (d *RESTClient) storeToken(token OAuthToken) OAuthToken {
+	if token.Expiry.IsZero() && token.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
 	}
+
+	d.mu.Lock()
+	d.token = token
+	d.tokenExpires = token.Expiry
+	d.mu.Unlock()
+
+	d.SetBearerToken(token.AccessToken)
+	return token
 }
 
 // CreateAccessKey does what it says on the tin. The client
 // should already be authenticated somehow, by calling either
 // Authenticate/RefreshAuth/SetBearerToken
 func (d *RESTClient) CreateAccessKey(name string) (*AccessKey, error) {
+	return d.CreateAccessKeyContext(context.Background(), name)
+}
+
+// CreateAccessKeyContext is the same as CreateAccessKey, bound to `ctx`
+func (d *RESTClient) CreateAccessKeyContext(ctx context.Context, name string) (*AccessKey, error) {
 	var key AccessKey
 
 	// key names are not required, but make life much easier
@@ -65,197 +171,292 @@ func (d *RESTClient) CreateAccessKey(name string) (*AccessKey, error) {
 		return nil, errors.New(ErrorInvalidKeyName)
 	}
 
-	_, err := d.hclient.Post("",
+	_, err := d.hclient.PostJSONContext(ctx, "",
 		h.Navigate{"accesskeys"},
 		nil,
-		bytes.NewBuffer([]byte(fmt.Sprintf(`{"Name":"%s"}`, name))),
+		struct {
+			Name string `json:"Name"`
+		}{name},
 		&key)
 
-	return &key, err
+	return &key, asHTTPError(err)
 }
 
 // DeleteAccessKey does what it says on the tin
 func (d *RESTClient) DeleteAccessKey(key *AccessKey) error {
-	return d.DeleteSelf(&key.Links)
+	return d.DeleteAccessKeyContext(context.Background(), key)
+}
+
+// DeleteAccessKeyContext is the same as DeleteAccessKey, bound to `ctx`
+func (d *RESTClient) DeleteAccessKeyContext(ctx context.Context, key *AccessKey) error {
+	return d.DeleteSelfContext(ctx, &key.Links)
 }
 
 // GetAccessKeys returns the list of accesskeys in this organisation
 func (d *RESTClient) GetAccessKeys(previous *AccessKeys) (*AccessKeys, error) {
+	return d.GetAccessKeysContext(context.Background(), previous)
+}
+
+// GetAccessKeysContext is the same as GetAccessKeys, bound to `ctx`
+func (d *RESTClient) GetAccessKeysContext(ctx context.Context, previous *AccessKeys) (*AccessKeys, error) {
 	if previous == nil {
 		var keys AccessKeys
-		_, err := d.hclient.Get("",
+		_, err := d.hclient.GetContext(ctx, "",
 			h.Navigate{"accesskeys"},
 			nil,
 			nil,
 			&keys)
-		return &keys, err
+		return &keys, asHTTPError(err)
 	}
 
-	next, err := previous.PageInfo.Links.Get("next")
-	if errors.Cause(err).Error() == h.ErrorLinkNotFound {
+	var keys AccessKeys
+	more, err := d.getNext(ctx, previous, &keys)
+	if !more {
 		return nil, nil
 	}
-
-	var keys AccessKeys
-	_, err = d.hclient.Get(next.Href,
-		nil,
-		nil,
-		nil,
-		&keys)
 	return &keys, err
 }
 
+// AllAccessKeys iterates over every accesskey in this organisation, fetching
+// further pages as required
+func (d *RESTClient) AllAccessKeys(ctx context.Context) iter.Seq2[AccessKey, error] {
+	return items(h.Items[AccessKey, AccessKeys](ctx, d.hclient, "", h.Navigate{"accesskeys"}))
+}
+
+// getNext fetches the page following `previous` into `page`, returning false
+// if there isn't one
+func (d *RESTClient) getNext(ctx context.Context, previous h.PagedResource, page interface{}) (bool, error) {
+	next, err := previous.PageLinks().Get("next")
+	if err != nil {
+		return false, nil
+	}
+	_, err = d.hclient.GetContext(ctx, next.Href, nil, nil, nil, page)
+	return true, asHTTPError(err)
+}
+
+// items converts the errors of a hateoas iterator into HTTPError where appropriate
+func items[T any](seq iter.Seq2[T, error]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for item, err := range seq {
+			if !yield(item, asHTTPError(err)) {
+				return
+			}
+		}
+	}
+}
+
 // Authenticate uses the provided key/secret to obtain an access_token/refresh_token
 func (d *RESTClient) Authenticate(credentials *AccessKey) error {
-	var token OAuthToken
-	_, err := d.hclient.PostForm("",
-		h.Navigate{"authenticate"},
-		nil,
-		url.Values{
-			"grant_type": []string{"password"},
-			"username":   []string{credentials.Key},
-			"password":   []string{credentials.Secret},
-		},
-		&token)
+	return d.AuthenticateContext(context.Background(), credentials)
+}
+
+// AuthenticateContext is the same as Authenticate, bound to `ctx`
+func (d *RESTClient) AuthenticateContext(ctx context.Context, credentials *AccessKey) error {
+	token, err := passwordGrant(ctx, d.hclient, credentials)
 	if err == nil {
-		d.token = token
-		d.tokenExpires = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
-		d.SetBearerToken(token.AccessToken)
+		// kept in case the refresh token is ever rejected
+		d.mu.Lock()
+		d.credentials = &AccessKey{Key: credentials.Key, Secret: credentials.Secret}
+		d.source = nil
+		d.mu.Unlock()
+		d.setToken(*token)
 	}
 	return err
 }
 
 // RefreshAuth uses the provided refresh_token obtain an access_token/refresh_token
 func (d *RESTClient) RefreshAuth(refreshToken string) error {
+	return d.RefreshAuthContext(context.Background(), refreshToken)
+}
+
+// RefreshAuthContext is the same as RefreshAuth, bound to `ctx`
+func (d *RESTClient) RefreshAuthContext(ctx context.Context, refreshToken string) error {
+	token, err := refreshGrant(ctx, d.hclient, refreshToken)
+	if err == nil {
+		d.setToken(*token)
+	}
+	return err
+}
+
+// passwordGrant obtains a token for an access key/secret
+func passwordGrant(ctx context.Context, hclient *h.Client, credentials *AccessKey) (*OAuthToken, error) {
+	return grant(ctx, hclient, url.Values{
+		"grant_type": []string{"password"},
+		"username":   []string{credentials.Key},
+		"password":   []string{credentials.Secret},
+	})
+}
+
+// refreshGrant exchanges a refresh_token for a new token
+func refreshGrant(ctx context.Context, hclient *h.Client, refreshToken string) (*OAuthToken, error) {
+	return grant(ctx, hclient, url.Values{
+		"grant_type":    []string{"refresh_token"},
+		"refresh_token": []string{refreshToken},
+	})
+}
+
+func grant(ctx context.Context, hclient *h.Client, form url.Values) (*OAuthToken, error) {
 	var token OAuthToken
-	_, err := d.hclient.PostForm("",
+	_, err := hclient.PostFormContext(withoutRefresh(ctx), "",
 		h.Navigate{"authenticate"},
 		nil,
-		url.Values{
-			"grant_type":    []string{"refresh_token"},
-			"refresh_token": []string{refreshToken},
-		},
+		form,
 		&token)
-	if err == nil {
-		d.token = token
-		d.tokenExpires = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
-		d.SetBearerToken(token.AccessToken)
+	if err != nil {
+		return nil, asHTTPError(err)
 	}
-	return err
+	if token.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	return &token, nil
 }
 
 func (d *RESTClient) GetClients(previous *Clients) (*Clients, error) {
+	return d.GetClientsContext(context.Background(), previous)
+}
+
+// GetClientsContext is the same as GetClients, bound to `ctx`
+func (d *RESTClient) GetClientsContext(ctx context.Context, previous *Clients) (*Clients, error) {
 	if previous == nil {
 		var clients Clients
-		_, err := d.hclient.Get("",
+		_, err := d.hclient.GetContext(ctx, "",
 			h.Navigate{"clients"},
 			nil,
 			nil,
 			&clients)
 
-		return &clients, err
+		return &clients, asHTTPError(err)
 	}
 
-	next, err := previous.PageInfo.Links.Get("next")
-	if errors.Cause(err).Error() == h.ErrorLinkNotFound {
+	var clients Clients
+	more, err := d.getNext(ctx, previous, &clients)
+	if !more {
 		return nil, nil
 	}
-
-	var clients Clients
-	_, err = d.hclient.Get(next.Href,
-		nil,
-		nil,
-		nil,
-		&clients)
 	return &clients, err
 }
 
+// AllClients iterates over every client, fetching further pages as required
+func (d *RESTClient) AllClients(ctx context.Context) iter.Seq2[Client, error] {
+	return items(h.Items[Client, Clients](ctx, d.hclient, "", h.Navigate{"clients"}))
+}
+
 func (d *RESTClient) GetObjectTypes(c *Client) (*ObjectTypes, error) {
+	return d.GetObjectTypesContext(context.Background(), c)
+}
+
+// GetObjectTypesContext is the same as GetObjectTypes, bound to `ctx`
+func (d *RESTClient) GetObjectTypesContext(ctx context.Context, c *Client) (*ObjectTypes, error) {
 	var o ObjectTypes
-	_, err := d.hclient.Get(c.Links.Self(),
+	_, err := d.hclient.GetContext(ctx, c.Links.Self(),
 		h.Navigate{"objecttypes"},
 		nil,
 		nil,
 		&o)
-	return &o, err
+	return &o, asHTTPError(err)
 }
 
 func (d *RESTClient) GetObjectInstances(o *ObjectType) (*ObjectInstances, error) {
+	return d.GetObjectInstancesContext(context.Background(), o)
+}
+
+// GetObjectInstancesContext is the same as GetObjectInstances, bound to `ctx`
+func (d *RESTClient) GetObjectInstancesContext(ctx context.Context, o *ObjectType) (*ObjectInstances, error) {
 	var i ObjectInstances
-	_, err := d.hclient.Get(o.Links.Self(),
+	_, err := d.hclient.GetContext(ctx, o.Links.Self(),
 		h.Navigate{"instances"},
 		nil,
 		nil,
 		&i)
-	return &i, err
+	return &i, asHTTPError(err)
 }
 
 func (d *RESTClient) GetSubscriptions(endpoint string, previous *Subscriptions) (*Subscriptions, error) {
+	return d.GetSubscriptionsContext(context.Background(), endpoint, previous)
+}
+
+// GetSubscriptionsContext is the same as GetSubscriptions, bound to `ctx`
+func (d *RESTClient) GetSubscriptionsContext(ctx context.Context, endpoint string, previous *Subscriptions) (*Subscriptions, error) {
 	if endpoint != "" && previous != nil {
 		return nil, errors.New("cannot get subscriptions for endpoint and previous")
 	}
 
 	if previous == nil {
 		var subs Subscriptions
-		_, err := d.hclient.Get(endpoint,
+		_, err := d.hclient.GetContext(ctx, endpoint,
 			h.Navigate{"subscriptions"},
 			nil,
 			nil,
 			&subs)
 
-		return &subs, err
+		return &subs, asHTTPError(err)
 	}
 
-	next, err := previous.PageInfo.Links.Get("next")
-	if errors.Cause(err).Error() == h.ErrorLinkNotFound {
+	var subs Subscriptions
+	more, err := d.getNext(ctx, previous, &subs)
+	if !more {
 		return nil, nil
 	}
-
-	var subs Subscriptions
-	_, err = d.hclient.Get(next.Href,
-		nil,
-		nil,
-		nil,
-		&subs)
 	return &subs, err
 }
 
+// AllSubscriptions iterates over every subscription on `endpoint` (as per GetSubscriptions),
+// fetching further pages as required
+func (d *RESTClient) AllSubscriptions(ctx context.Context, endpoint string) iter.Seq2[SubscriptionRequest, error] {
+	return items(h.Items[SubscriptionRequest, Subscriptions](ctx, d.hclient, endpoint, h.Navigate{"subscriptions"}))
+}
+
 // Subscribe sets up webhook subscriptions, i.e. COAP observations.
 // The `endpoint` can be
 // - "" (=entrypoint) to subscribe to ClientConnected/ClientDisconnected events
 // - a specific resource "self" URL to subscribe to observations on that resource
 func (d *RESTClient) Subscribe(endpoint string, req *SubscriptionRequest, resp *SubscriptionResponse) error {
-	buf, err := json.Marshal(req)
-	if err != nil {
-		return err
-	}
+	return d.SubscribeContext(context.Background(), endpoint, req, resp)
+}
 
-	_, err = d.hclient.Post(endpoint,
+// SubscribeContext is the same as Subscribe, bound to `ctx`
+func (d *RESTClient) SubscribeContext(ctx context.Context, endpoint string, req *SubscriptionRequest, resp *SubscriptionResponse) error {
+	_, err := d.hclient.PostJSONContext(ctx, endpoint,
 		h.Navigate{"subscriptions"},
 		h.Headers{"Content-Type": "application/vnd.oma.lwm2m.subscription+json"},
-		bytes.NewBuffer(buf),
+		req,
 		resp)
 
-	return err
+	return asHTTPError(err)
 }
 
 func (d *RESTClient) Unsubscribe(subscription *SubscriptionResponse) error {
-	return d.DeleteSelf(&subscription.Links)
+	return d.UnsubscribeContext(context.Background(), subscription)
+}
+
+// UnsubscribeContext is the same as Unsubscribe, bound to `ctx`
+func (d *RESTClient) UnsubscribeContext(ctx context.Context, subscription *SubscriptionResponse) error {
+	return d.DeleteSelfContext(ctx, &subscription.Links)
 }
 
 // Delete performs DELETE on the specified resource
 func (d *RESTClient) Delete(endpoint string) error {
-	_, err := d.hclient.Delete(endpoint, nil, nil, nil, nil)
-	return err
+	return d.DeleteContext(context.Background(), endpoint)
+}
+
+// DeleteContext is the same as Delete, bound to `ctx`
+func (d *RESTClient) DeleteContext(ctx context.Context, endpoint string) error {
+	_, err := d.hclient.DeleteContext(ctx, endpoint, nil, nil, nil, nil)
+	return asHTTPError(err)
 }
 
 // DeleteSelf will find the "self" link and DELETE that
 func (d *RESTClient) DeleteSelf(links *h.Links) error {
+	return d.DeleteSelfContext(context.Background(), links)
+}
+
+// DeleteSelfContext is the same as DeleteSelf, bound to `ctx`
+func (d *RESTClient) DeleteSelfContext(ctx context.Context, links *h.Links) error {
 	self, err := links.Get("self")
 	if err != nil {
 		return nil
 	}
-	return d.Delete(self.Href)
+	return d.DeleteContext(ctx, self.Href)
 }
 
 // HATEOAS exposes the underlying hateoas client so that you
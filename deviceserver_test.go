@@ -4,10 +4,15 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/CreatorKit/go-deviceserver-client/hateoas"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -16,25 +21,66 @@ var (
 	deviceserverPSK = os.Getenv("DEVICESERVER_PSK")
 )
 
-func init() {
-	if deviceserverURL == "" {
-		fmt.Println("Please provide env var DEVICESERVER_URL")
-		os.Exit(1)
+// testServer returns the entry URL, PSK and HTTPDoer for a test which needs a deviceserver.
+// Given DEVICESERVER_URL and DEVICESERVER_PSK the test talks to that server, recording into
+// testdata/<test>.json if DEVICESERVER_RECORD is also set; otherwise it replays that cassette.
+//
+// The cassettes in testdata were not recorded against a real deviceserver, but against a
+// local stand-in on localhost:8080 implementing just the endpoints these tests use, with
+// made-up keys and IDs. Replaying them checks the client's requests and its handling of
+// the responses, not compatibility with the deviceserver itself; for that, re-record with
+//
+//	DEVICESERVER_URL=... DEVICESERVER_PSK=... DEVICESERVER_RECORD=1 go test -run 'TestAuth|TestSubscriptions'
+//
+// Recording scrubs secrets, tokens and passwords (see hateoas.ScrubSecrets), which
+// TestCassettesScrubbed checks before anything is committed.
+func testServer(t *testing.T) (string, string, hateoas.HTTPDoer) {
+	path := filepath.Join("testdata", t.Name()+".json")
+
+	if deviceserverURL == "" || deviceserverPSK == "" {
+		cassette, err := hateoas.LoadCassette(path)
+		if err != nil || len(cassette.Interactions) == 0 {
+			t.Skipf("no DEVICESERVER_URL/DEVICESERVER_PSK, and no cassette to replay: %v", err)
+		}
+		replayer := hateoas.CreateReplayer(cassette)
+		t.Cleanup(func() {
+			assert.Zero(t, replayer.Remaining(), "recorded interactions were not replayed")
+		})
+		return cassette.Interactions[0].Request.URL, "replayed", replayer
 	}
-	if deviceserverPSK == "" {
-		fmt.Println("Please provide env var DEVICESERVER_PSK")
-		os.Exit(1)
+
+	if os.Getenv("DEVICESERVER_RECORD") == "" {
+		return deviceserverURL, deviceserverPSK, &http.Client{}
+	}
+	recorder := hateoas.CreateRecorder(nil)
+	t.Cleanup(func() {
+		assert.Nil(t, recorder.Save(path))
+	})
+	return deviceserverURL, deviceserverPSK, recorder
+}
+
+func TestCassettesScrubbed(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.json"))
+	assert.Nil(t, err)
+	for _, path := range paths {
+		cassette, err := hateoas.LoadCassette(path)
+		assert.Nil(t, err, path)
+		for i, interaction := range cassette.Interactions {
+			scrubbed := interaction
+			hateoas.ScrubSecrets(&scrubbed)
+			assert.Equal(t, scrubbed, interaction, "%s interaction %d has unscrubbed secrets", path, i)
+		}
 	}
 }
 
 type httpLogger struct {
-	http.Client
+	Http   hateoas.HTTPDoer
 	logged []string
 	dump   bool
 }
 
 func (h *httpLogger) Do(req *http.Request) (*http.Response, error) {
-	resp, err := h.Client.Do(req)
+	resp, err := h.Http.Do(req)
 	var s string
 	if resp != nil {
 		s = fmt.Sprintf("%s %s %d", req.Method, req.URL.String(), resp.StatusCode)
@@ -50,9 +96,10 @@ func (h *httpLogger) Do(req *http.Request) (*http.Response, error) {
 
 // TestAuth handles everything to do with keys and tokens etc
 func TestAuth(t *testing.T) {
-	logger := &httpLogger{dump: true}
+	entryURL, psk, doer := testServer(t)
+	logger := &httpLogger{Http: doer, dump: true}
 	d, err := Create(hateoas.Create(&hateoas.Client{
-		EntryURL: deviceserverURL,
+		EntryURL: entryURL,
 		Http:     logger,
 	}))
 	assert.Nil(t, err)
@@ -60,7 +107,7 @@ func TestAuth(t *testing.T) {
 	defer d.Close()
 
 	// set token from admin PSK in order to create the first key
-	token, _ := TokenFromPSK(deviceserverPSK, 0)
+	token, _ := TokenFromPSK(psk, 0)
 	d.SetBearerToken(token)
 
 	k, err := d.CreateAccessKey("bob")
@@ -80,7 +127,7 @@ func TestAuth(t *testing.T) {
 	_, err = entry.Links.Get("accesskeys")
 	assert.NotNil(t, err) // when not authenticated, should not be able to get accesskeys
 
-	err = ds.Authenticate(k)
+	err = d.Authenticate(k)
 	assert.Nil(t, err)
 
 	keys, err := d.GetAccessKeys(nil)
@@ -112,16 +159,17 @@ func TestAuth(t *testing.T) {
 }
 
 func TestSubscriptions(t *testing.T) {
-	logger := &httpLogger{dump: true}
+	entryURL, psk, doer := testServer(t)
+	logger := &httpLogger{Http: doer, dump: true}
 	d, err := Create(hateoas.Create(&hateoas.Client{
-		EntryURL: deviceserverURL,
+		EntryURL: entryURL,
 		Http:     logger,
 	}))
 	assert.Nil(t, err)
 	assert.NotNil(t, d)
 	defer d.Close()
 
-	token, _ := TokenFromPSK(deviceserverPSK, 0)
+	token, _ := TokenFromPSK(psk, 0)
 	d.SetBearerToken(token)
 
 	k, err := d.CreateAccessKey("bob")
@@ -147,3 +195,77 @@ func TestSubscriptions(t *testing.T) {
 	err = d.DeleteAccessKey(k)
 	assert.Nil(t, err)
 }
+
+func TestHTTPError(t *testing.T) {
+	herr := &hateoas.HTTPError{
+		StatusCode: http.StatusConflict,
+		Method:     "POST",
+		URL:        "http://localhost/accesskeys",
+		Body:       []byte(`{"ErrorCode":"Conflict","ErrorMessage":"already exists"}`),
+	}
+	err := asHTTPError(herr)
+
+	var dserr *HTTPError
+	assert.True(t, errors.As(err, &dserr))
+	assert.Equal(t, "Conflict", dserr.ServerError.ErrorCode)
+	assert.Equal(t, "already exists", dserr.ServerError.ErrorMessage)
+	assert.Equal(t, http.StatusConflict, dserr.StatusCode)
+	assert.True(t, hateoas.IsConflict(err))
+
+	assert.Nil(t, asHTTPError(nil))
+}
+
+func TestConcurrentTokens(t *testing.T) {
+	auth := make(chan string, 1000)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth <- r.Header.Get("Authorization")
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprintf(w, `{"Links":[{"rel":"authenticate","href":"/oauth/token"},{"rel":"clients","href":"/clients"}]}`)
+		case "/oauth/token":
+			fmt.Fprint(w, `{"access_token":"fresh","token_type":"bearer","expires_in":3600,"refresh_token":"refresh"}`)
+		default:
+			fmt.Fprint(w, `{"Items":[]}`)
+		}
+	}))
+	defer ts.Close()
+
+	d, err := Create(hateoas.Create(&hateoas.Client{
+		EntryURL: ts.URL,
+	}))
+	assert.Nil(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			assert.Nil(t, d.RefreshAuth("refresh"))
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := d.GetClients(nil)
+			assert.Nil(t, err)
+		}()
+	}
+	wg.Wait()
+
+	token, expires := d.Token()
+	assert.Equal(t, "fresh", token.AccessToken)
+	assert.True(t, expires.After(time.Now()))
+
+	// each GetClients is 2 requests, the entrypoint and then the clients
+	clone := d.Clone()
+	clone.SetBearerToken("other")
+	for len(auth) > 0 {
+		<-auth
+	}
+	_, err = d.GetClients(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "Bearer fresh", <-auth)
+	assert.Equal(t, "Bearer fresh", <-auth)
+	_, err = clone.GetClients(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "Bearer other", <-auth)
+	assert.Equal(t, "Bearer other", <-auth)
+}
@@ -0,0 +1,65 @@
+package deviceserver
+
+import (
+	"fmt"
+
+	h "github.com/CreatorKit/go-deviceserver-client/hateoas"
+	"github.com/pkg/errors"
+)
+
+// HTTPError is returned by RESTClient calls that fail with a HTTP status >= 400.
+// It embeds the underlying hateoas.HTTPError (status, method, URL and raw body)
+// and adds the deviceserver's own Error payload, if one was sent.
+// The hateoas predicates (IsNotFound, IsUnauthorized etc) work on it too.
+type HTTPError struct {
+	*h.HTTPError
+	ServerError Error
+}
+
+func (e *HTTPError) Error() string {
+	if e.ServerError.ErrorCode == "" && e.ServerError.ErrorMessage == "" {
+		return e.HTTPError.Error()
+	}
+	return fmt.Sprintf("%s: %s %s", e.HTTPError.Error(), e.ServerError.ErrorCode, e.ServerError.ErrorMessage)
+}
+
+// Unwrap allows errors.As to find the underlying hateoas.HTTPError
+func (e *HTTPError) Unwrap() error {
+	return e.HTTPError
+}
+
+// AuthError is returned when a request refused with a 401 could not be recovered by
+// re-authenticating: either re-authenticating failed, or the replayed request was
+// refused too. Err is the cause, which for the latter is the final 401 HTTPError,
+// so hateoas.IsUnauthorized still works.
+type AuthError struct {
+	Err error
+}
+
+func (e *AuthError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrorAuthFailed, e.Err.Error())
+}
+
+// Unwrap returns the cause
+func (e *AuthError) Unwrap() error {
+	return e.Err
+}
+
+// IsAuthError reports whether `err` is (or wraps) an AuthError
+func IsAuthError(err error) bool {
+	var aerr *AuthError
+	return errors.As(err, &aerr)
+}
+
+// asHTTPError converts a hateoas.HTTPError into a HTTPError, decoding the
+// deviceserver Error payload where possible. Any other error is returned as is.
+func asHTTPError(err error) error {
+	var herr *h.HTTPError
+	if !errors.As(err, &herr) || IsAuthError(err) {
+		return err
+	}
+	e := HTTPError{HTTPError: herr}
+	// not every error response has a body, so this is best effort
+	herr.Decode(&e.ServerError)
+	return &e
+}
@@ -0,0 +1,244 @@
+package hateoas
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// CircuitState is the state of a CircuitBreaker
+type CircuitState int
+
+const (
+	// CircuitClosed passes requests through as normal
+	CircuitClosed CircuitState = iota
+	// CircuitOpen fails requests immediately with a *CircuitOpenError
+	CircuitOpen
+	// CircuitHalfOpen lets a single probe request through to decide whether to close again
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("CircuitState(%d)", int(s))
+}
+
+// CircuitOpenError is returned, without making a request, while a CircuitBreaker is open
+type CircuitOpenError struct {
+	// RetryAt is when the breaker will next let a probe request through
+	RetryAt time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker open until %s", e.RetryAt.Format(time.RFC3339))
+}
+
+// IsCircuitOpen is true when `err` is, or wraps, a *CircuitOpenError
+func IsCircuitOpen(err error) bool {
+	var oerr *CircuitOpenError
+	return errors.As(err, &oerr)
+}
+
+// CircuitBreaker is a HTTPDoer which stops sending requests to a server that is
+// failing, so callers fail fast rather than each waiting out a timeout.
+//
+// It opens after MaxConsecutiveFailures failures in a row, or when at least
+// MinRequests within Window have failed at FailureRate or more. After Cooldown
+// it half-opens and lets a single probe through: success closes it, failure
+// opens it again. Requests abandoned by their own context are not counted.
+type CircuitBreaker struct {
+	Http HTTPDoer
+
+	// MaxConsecutiveFailures of 0 disables tripping on consecutive failures
+	MaxConsecutiveFailures int
+
+	// FailureRate or Window of 0 disables tripping on the failure rate
+	FailureRate float64
+	MinRequests int
+	Window      time.Duration
+
+	Cooldown time.Duration
+
+	// IsFailure defaults to connection errors and 5xx responses
+	IsFailure func(resp *http.Response, err error) bool
+
+	// OnStateChange is called after every transition, without any locks held
+	OnStateChange func(from CircuitState, to CircuitState)
+
+	mu          sync.Mutex
+	state       CircuitState
+	consecutive int
+	openedAt    time.Time
+	probing     bool
+	outcomes    []circuitOutcome
+}
+
+type circuitOutcome struct {
+	at     time.Time
+	failed bool
+}
+
+// CreateCircuitBreaker wraps `doer` (defaulting to a plain *http.Client), opening after
+// 5 consecutive failures or half of at least 20 requests in a minute, for 30s at a time
+func CreateCircuitBreaker(doer HTTPDoer) *CircuitBreaker {
+	if doer == nil {
+		doer = &http.Client{}
+	}
+	return &CircuitBreaker{
+		Http:                   doer,
+		MaxConsecutiveFailures: 5,
+		FailureRate:            0.5,
+		MinRequests:            20,
+		Window:                 time.Minute,
+		Cooldown:               30 * time.Second,
+	}
+}
+
+func defaultIsFailure(resp *http.Response, err error) bool {
+	return err != nil || resp.StatusCode >= http.StatusInternalServerError
+}
+
+// State returns the current state, moving from open to half-open once the cooldown has passed
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	from := b.state
+	to := b.cool()
+	b.mu.Unlock()
+
+	b.changed(from, to)
+	return to
+}
+
+func (b *CircuitBreaker) changed(from CircuitState, to CircuitState) {
+	if from != to && b.OnStateChange != nil {
+		b.OnStateChange(from, to)
+	}
+}
+
+// cool half-opens an open breaker after the cooldown, and must be called with b.mu held
+func (b *CircuitBreaker) cool() CircuitState {
+	if b.state == CircuitOpen && !time.Now().Before(b.openedAt.Add(b.Cooldown)) {
+		b.state = CircuitHalfOpen
+		b.probing = false
+	}
+	return b.state
+}
+
+// trip opens the breaker, and must be called with b.mu held
+func (b *CircuitBreaker) trip() {
+	b.state = CircuitOpen
+	b.openedAt = time.Now()
+	b.consecutive = 0
+	b.outcomes = nil
+}
+
+// allow decides whether a request may go ahead, returning whether it is the half-open probe
+func (b *CircuitBreaker) allow() (bool, error) {
+	b.mu.Lock()
+	from := b.state
+	state := b.cool()
+	allowed, probe := true, false
+	switch state {
+	case CircuitOpen:
+		allowed = false
+	case CircuitHalfOpen:
+		if b.probing {
+			allowed = false
+		} else {
+			b.probing = true
+			probe = true
+		}
+	}
+	retryAt := b.openedAt.Add(b.Cooldown)
+	b.mu.Unlock()
+
+	b.changed(from, state)
+	if !allowed {
+		return false, &CircuitOpenError{RetryAt: retryAt}
+	}
+	return probe, nil
+}
+
+// record counts the outcome of a request, tripping or closing the breaker as required
+func (b *CircuitBreaker) record(probe bool, failed bool) {
+	b.mu.Lock()
+	from := b.state
+	now := time.Now()
+
+	switch {
+	case probe && failed:
+		b.trip()
+	case probe:
+		b.state = CircuitClosed
+		b.probing = false
+		b.consecutive = 0
+		b.outcomes = nil
+	case b.state == CircuitClosed:
+		if failed {
+			b.consecutive++
+		} else {
+			b.consecutive = 0
+		}
+		// without a Window the outcomes would grow forever, so the rate isn't tracked
+		rate := b.FailureRate > 0 && b.Window > 0
+		if rate {
+			b.outcomes = append(b.outcomes, circuitOutcome{now, failed})
+			for len(b.outcomes) > 0 && now.Sub(b.outcomes[0].at) > b.Window {
+				b.outcomes = b.outcomes[1:]
+			}
+		}
+
+		if b.MaxConsecutiveFailures > 0 && b.consecutive >= b.MaxConsecutiveFailures {
+			b.trip()
+		} else if rate && len(b.outcomes) >= b.MinRequests {
+			failures := 0
+			for _, outcome := range b.outcomes {
+				if outcome.failed {
+					failures++
+				}
+			}
+			if float64(failures)/float64(len(b.outcomes)) >= b.FailureRate {
+				b.trip()
+			}
+		}
+	}
+	to := b.state
+	b.mu.Unlock()
+
+	b.changed(from, to)
+}
+
+// Do implements HTTPDoer
+func (b *CircuitBreaker) Do(req *http.Request) (*http.Response, error) {
+	probe, err := b.allow()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := b.Http.Do(req)
+	if req.Context().Err() != nil {
+		if probe {
+			b.mu.Lock()
+			b.probing = false
+			b.mu.Unlock()
+		}
+		return resp, err
+	}
+
+	isFailure := b.IsFailure
+	if isFailure == nil {
+		isFailure = defaultIsFailure
+	}
+	b.record(probe, isFailure(resp, err))
+	return resp, err
+}
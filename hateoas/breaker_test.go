@@ -0,0 +1,129 @@
+package hateoas
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type switchableDoer struct {
+	mu     sync.Mutex
+	status int
+	err    error
+	hits   int
+}
+
+func (s *switchableDoer) set(status int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status, s.err = status, err
+}
+
+func (s *switchableDoer) Do(req *http.Request) (*http.Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hits++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &http.Response{StatusCode: s.status, Header: http.Header{}, Request: req}, nil
+}
+
+func breakerRequest(doer HTTPDoer) error {
+	req, _ := http.NewRequest("GET", "http://a/", nil)
+	_, err := doer.Do(req)
+	return err
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	assert := assert.New(t)
+
+	server := &switchableDoer{status: http.StatusOK}
+	b := CreateCircuitBreaker(server)
+	b.MaxConsecutiveFailures = 3
+	b.Cooldown = 20 * time.Millisecond
+	var changes []string
+	b.OnStateChange = func(from CircuitState, to CircuitState) {
+		changes = append(changes, from.String()+">"+to.String())
+	}
+
+	assert.Nil(breakerRequest(b))
+	server.set(http.StatusBadGateway, nil)
+	for i := 0; i < 3; i++ {
+		assert.Nil(breakerRequest(b))
+	}
+	assert.Equal(CircuitOpen, b.State())
+
+	// fails fast while open
+	err := breakerRequest(b)
+	assert.True(IsCircuitOpen(err))
+	assert.True(IsCircuitOpen(errors.Wrap(err, "wrapped")))
+	assert.Equal(4, server.hits)
+
+	// a failed probe opens again
+	time.Sleep(25 * time.Millisecond)
+	assert.Equal(CircuitHalfOpen, b.State())
+	assert.Nil(breakerRequest(b))
+	assert.Equal(CircuitOpen, b.State())
+	assert.True(IsCircuitOpen(breakerRequest(b)))
+
+	// and a successful one closes
+	server.set(0, errors.New("connection refused"))
+	time.Sleep(25 * time.Millisecond)
+	server.set(http.StatusOK, nil)
+	assert.Nil(breakerRequest(b))
+	assert.Equal(CircuitClosed, b.State())
+	assert.Equal([]string{"closed>open", "open>half-open", "half-open>open", "open>half-open", "half-open>closed"}, changes)
+}
+
+func TestCircuitBreakerFailureRate(t *testing.T) {
+	assert := assert.New(t)
+
+	server := &switchableDoer{}
+	b := CreateCircuitBreaker(server)
+	b.MaxConsecutiveFailures = 0
+	b.FailureRate = 0.5
+	b.MinRequests = 6
+
+	// alternating failures are never consecutive, but are half of the requests
+	for i := 0; i < 5; i++ {
+		server.set(http.StatusOK+(i%2)*300, nil)
+		assert.Nil(breakerRequest(b))
+		assert.Equal(CircuitClosed, b.State())
+	}
+	server.set(http.StatusInternalServerError, nil)
+	assert.Nil(breakerRequest(b))
+	assert.Equal(CircuitOpen, b.State())
+
+	// no Window means no failure rate, and nothing recorded
+	b = CreateCircuitBreaker(server)
+	b.MaxConsecutiveFailures = 0
+	b.Window = 0
+	for i := 0; i < 20; i++ {
+		assert.Nil(breakerRequest(b))
+	}
+	assert.Equal(CircuitClosed, b.State())
+	assert.Empty(b.outcomes)
+}
+
+func TestCircuitBreakerContext(t *testing.T) {
+	assert := assert.New(t)
+
+	b := CreateCircuitBreaker(DoerFunc(func(req *http.Request) (*http.Response, error) {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	}))
+	b.MaxConsecutiveFailures = 1
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", "http://a/", nil)
+	_, err := b.Do(req)
+	assert.Equal(context.Canceled, err)
+	assert.Equal(CircuitClosed, b.State())
+}
@@ -0,0 +1,332 @@
+package hateoas
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrorNoInteraction = "no matching recorded interaction"
+)
+
+// RecordedRequest is the request half of an Interaction
+type RecordedRequest struct {
+	Method string      `json:"Method"`
+	URL    string      `json:"URL"`
+	Header http.Header `json:"Header,omitempty"`
+	Body   string      `json:"Body,omitempty"`
+}
+
+// RecordedResponse is the response half of an Interaction
+type RecordedResponse struct {
+	StatusCode int         `json:"StatusCode"`
+	Header     http.Header `json:"Header,omitempty"`
+	Body       string      `json:"Body,omitempty"`
+}
+
+// Interaction is a single request/response pair
+type Interaction struct {
+	Request  RecordedRequest  `json:"Request"`
+	Response RecordedResponse `json:"Response"`
+}
+
+// Cassette is a sequence of interactions, as saved by a Recorder and served by a Replayer
+type Cassette struct {
+	Interactions []Interaction `json:"Interactions"`
+}
+
+// LoadCassette reads a cassette saved with Cassette.Save
+func LoadCassette(path string) (*Cassette, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Cassette
+	err = json.Unmarshal(buf, &c)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Save writes the cassette as indented JSON, so that diffs are readable
+func (c *Cassette) Save(path string) error {
+	buf, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(buf, '\n'), 0644)
+}
+
+// DefaultScrubbedFields are the JSON properties and form fields whose values
+// ScrubSecrets replaces, matched case-insensitively
+var DefaultScrubbedFields = []string{"Secret", "password", "access_token", "refresh_token"}
+
+// ScrubSecrets redacts an interaction before it is saved: the DefaultRedactedHeaders
+// in both directions, and DefaultScrubbedFields in JSON and form encoded bodies
+func ScrubSecrets(i *Interaction) {
+	i.Request.Header = RedactHeaders(i.Request.Header)
+	i.Response.Header = RedactHeaders(i.Response.Header)
+	i.Request.Body = scrubBody(i.Request.Body, DefaultScrubbedFields)
+	i.Response.Body = scrubBody(i.Response.Body, DefaultScrubbedFields)
+}
+
+func scrubField(name string, fields []string) bool {
+	for _, field := range fields {
+		if strings.EqualFold(name, field) {
+			return true
+		}
+	}
+	return false
+}
+
+func scrubJSON(v interface{}, fields []string) {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		for name, value := range vv {
+			if _, isString := value.(string); isString && scrubField(name, fields) {
+				vv[name] = "REDACTED"
+				continue
+			}
+			scrubJSON(value, fields)
+		}
+	case []interface{}:
+		for _, value := range vv {
+			scrubJSON(value, fields)
+		}
+	}
+}
+
+// scrubBody redacts fields from a JSON or form encoded body, leaving anything else alone
+func scrubBody(body string, fields []string) string {
+	if body == "" {
+		return body
+	}
+	var doc interface{}
+	if json.Unmarshal([]byte(body), &doc) == nil {
+		scrubJSON(doc, fields)
+		buf, err := json.Marshal(doc)
+		if err != nil {
+			return body
+		}
+		return string(buf)
+	}
+	form, err := url.ParseQuery(body)
+	if err != nil || strings.ContainsAny(body, " {\n") {
+		return body
+	}
+	scrubbed := false
+	for name := range form {
+		if scrubField(name, fields) {
+			form.Set(name, "REDACTED")
+			scrubbed = true
+		}
+	}
+	if !scrubbed {
+		return body
+	}
+	return form.Encode()
+}
+
+// Recorder is a HTTPDoer which passes requests on to Http, recording each
+// interaction into Cassette after passing it through Scrub.
+type Recorder struct {
+	Http     HTTPDoer
+	Cassette *Cassette
+
+	// Scrub redacts secrets from each interaction, and defaults to ScrubSecrets
+	Scrub func(*Interaction)
+
+	mu sync.Mutex
+}
+
+// CreateRecorder wraps `doer` (defaulting to a plain *http.Client) with an empty cassette
+func CreateRecorder(doer HTTPDoer) *Recorder {
+	if doer == nil {
+		doer = &http.Client{}
+	}
+	return &Recorder{
+		Http:     doer,
+		Cassette: &Cassette{},
+		Scrub:    ScrubSecrets,
+	}
+}
+
+// Do implements HTTPDoer
+func (r *Recorder) Do(req *http.Request) (*http.Response, error) {
+	var reqbody []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		reqbody, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(reqbody))
+	}
+
+	resp, err := r.Http.Do(req)
+	if err != nil {
+		return resp, err
+	}
+	respbody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return resp, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respbody))
+
+	i := Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Header: req.Header.Clone(),
+			Body:   string(reqbody),
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     resp.Header.Clone(),
+			Body:       string(respbody),
+		},
+	}
+	if r.Scrub != nil {
+		r.Scrub(&i)
+	}
+
+	r.mu.Lock()
+	r.Cassette.Interactions = append(r.Cassette.Interactions, i)
+	r.mu.Unlock()
+
+	return resp, nil
+}
+
+// Save writes everything recorded so far, see Cassette.Save
+func (r *Recorder) Save(path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.Cassette.Save(path)
+}
+
+// MatchFunc decides whether a recorded request can be replayed for `req`, whose body is given separately
+type MatchFunc func(req *http.Request, body []byte, recorded *RecordedRequest) bool
+
+// MatchMethod matches on the HTTP method
+func MatchMethod(req *http.Request, body []byte, recorded *RecordedRequest) bool {
+	return req.Method == recorded.Method
+}
+
+// MatchURL matches on the full URL, including the query
+func MatchURL(req *http.Request, body []byte, recorded *RecordedRequest) bool {
+	return req.URL.String() == recorded.URL
+}
+
+// MatchBody matches on the exact request body. Note that scrubbed bodies will no longer match.
+func MatchBody(req *http.Request, body []byte, recorded *RecordedRequest) bool {
+	return string(body) == recorded.Body
+}
+
+// MatchAll combines several MatchFunc, all of which must match
+func MatchAll(matchers ...MatchFunc) MatchFunc {
+	return func(req *http.Request, body []byte, recorded *RecordedRequest) bool {
+		for _, match := range matchers {
+			if !match(req, body, recorded) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Replayer is a HTTPDoer which serves responses from a Cassette rather than the network.
+// Each request is answered by the first interaction not yet replayed which matches, so a
+// cassette recorded in order replays in order, even when the same URL returns different
+// things over time.
+type Replayer struct {
+	Cassette *Cassette
+
+	// Match defaults to matching method and URL
+	Match MatchFunc
+
+	mu       sync.Mutex
+	replayed []bool
+}
+
+// CreateReplayer returns a Replayer for `cassette`, matching on method and URL
+func CreateReplayer(cassette *Cassette) *Replayer {
+	return &Replayer{
+		Cassette: cassette,
+		Match:    MatchAll(MatchMethod, MatchURL),
+	}
+}
+
+// Do implements HTTPDoer
+func (r *Replayer) Do(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	match := r.Match
+	if match == nil {
+		match = MatchAll(MatchMethod, MatchURL)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.replayed) != len(r.Cassette.Interactions) {
+		replayed := make([]bool, len(r.Cassette.Interactions))
+		copy(replayed, r.replayed)
+		r.replayed = replayed
+	}
+	for n := range r.Cassette.Interactions {
+		i := &r.Cassette.Interactions[n]
+		if r.replayed[n] || !match(req, body, &i.Request) {
+			continue
+		}
+		r.replayed[n] = true
+		header := i.Response.Header.Clone()
+		if header == nil {
+			header = http.Header{}
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", i.Response.StatusCode, http.StatusText(i.Response.StatusCode)),
+			StatusCode:    i.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          ioutil.NopCloser(strings.NewReader(i.Response.Body)),
+			ContentLength: int64(len(i.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, errors.Errorf("%s: %s %s", ErrorNoInteraction, req.Method, req.URL.String())
+}
+
+// Remaining returns how many interactions have not been replayed yet
+func (r *Replayer) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	remaining := len(r.Cassette.Interactions)
+	for _, replayed := range r.replayed {
+		if replayed {
+			remaining--
+		}
+	}
+	return remaining
+}
@@ -0,0 +1,90 @@
+package hateoas
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCassette(t *testing.T) {
+	assert := assert.New(t)
+
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprintf(w, `{"Links":[{"rel":"keys","href":"/keys"}],"Call":%d}`, calls)
+		case "/keys":
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"Key":"k","Secret":"hunter2","Nested":[{"refresh_token":"rt"}]}`)
+		}
+	}))
+	defer ts.Close()
+
+	recorder := CreateRecorder(nil)
+	c := Create(&Client{EntryURL: ts.URL, Http: recorder})
+	c.SetDefaultHeader("Authorization", "Bearer sekrit")
+
+	var first, second struct{ Call int }
+	_, err := c.Get("", nil, nil, nil, &first)
+	assert.Nil(err)
+	_, err = c.Get("", nil, nil, nil, &second)
+	assert.Nil(err)
+	var key struct{ Key, Secret string }
+	_, err = c.PostForm("", Navigate{"keys"}, nil, map[string][]string{"password": {"hunter2"}, "username": {"bob"}}, &key)
+	assert.Nil(err)
+	assert.Equal("hunter2", key.Secret) // only the recording is scrubbed
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	assert.Nil(recorder.Save(path))
+	buf, err := ioutil.ReadFile(path)
+	assert.Nil(err)
+	assert.False(bytes.Contains(buf, []byte("hunter2")))
+	assert.False(bytes.Contains(buf, []byte("sekrit")))
+	assert.False(bytes.Contains(buf, []byte(`"rt"`)))
+
+	cassette, err := LoadCassette(path)
+	assert.Nil(err)
+	assert.Len(cassette.Interactions, 4)
+	assert.Equal("REDACTED", cassette.Interactions[0].Request.Header.Get("Authorization"))
+	assert.Contains(cassette.Interactions[3].Request.Body, "username=bob")
+
+	// replayed in recorded order, without the server
+	calls = 0
+	replayer := CreateReplayer(cassette)
+	c = Create(&Client{EntryURL: ts.URL, Http: replayer})
+	_, err = c.Get("", nil, nil, nil, &first)
+	assert.Nil(err)
+	_, err = c.Get("", nil, nil, nil, &second)
+	assert.Nil(err)
+	assert.Equal(1, first.Call)
+	assert.Equal(2, second.Call)
+	key = struct{ Key, Secret string }{}
+	_, err = c.PostForm("", Navigate{"keys"}, nil, map[string][]string{"password": {"whatever"}}, &key)
+	assert.Nil(err)
+	assert.Equal("k", key.Key)
+	assert.Equal("REDACTED", key.Secret)
+	assert.Equal(0, calls)
+	assert.Equal(0, replayer.Remaining())
+
+	_, err = c.Get("", nil, nil, nil, &first)
+	assert.NotNil(err)
+
+	// matching on the body too
+	replayer = CreateReplayer(cassette)
+	replayer.Match = MatchAll(MatchMethod, MatchURL, MatchBody)
+	req, _ := http.NewRequest("POST", ts.URL+"/keys", bytes.NewBufferString("password=whatever"))
+	_, err = replayer.Do(req)
+	assert.NotNil(err)
+	req, _ = http.NewRequest("POST", ts.URL+"/keys", bytes.NewBufferString(cassette.Interactions[3].Request.Body))
+	resp, err := replayer.Do(req)
+	assert.Nil(err)
+	assert.Equal(http.StatusCreated, resp.StatusCode)
+}
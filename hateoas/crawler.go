@@ -0,0 +1,260 @@
+package hateoas
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// CrawlNode is a resource found by a Crawler
+type CrawlNode struct {
+	URL        string `json:"url"`
+	Depth      int    `json:"depth"`
+	StatusCode int    `json:"status,omitempty"`
+	Error      string `json:"error,omitempty"`
+	// Skipped says why the node was not fetched: "depth", "host", "templated" or "limit"
+	Skipped string `json:"skipped,omitempty"`
+}
+
+// CrawlEdge is a link from one node to another
+type CrawlEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Rel  string `json:"rel"`
+	// Item marks the link to a sampled member of a collection, rather than a rel
+	Item bool `json:"item,omitempty"`
+}
+
+// CrawlGraph is the map of an API built by Crawler.Crawl
+type CrawlGraph struct {
+	Entry string      `json:"entry"`
+	Nodes []CrawlNode `json:"nodes"`
+	Edges []CrawlEdge `json:"edges"`
+}
+
+// Crawler walks an API breadth-first from the Client's EntryURL, GETting every
+// link it finds once, to record which rels lead where for the Client's credentials.
+// Collections are sampled rather than walked in full: only the first SampleItems
+// members are visited, and SkipRels (by default the paging links) are not followed.
+type Crawler struct {
+	Client *Client
+
+	// MaxDepth is how many links away from the entry point to fetch
+	MaxDepth int
+	// MaxNodes stops the crawl running away on a large API
+	MaxNodes int
+	// SampleItems is how many members of each collection to visit
+	SampleItems int
+	// AllowedHosts defaults to just the host of the entry point
+	AllowedHosts []string
+	SkipRels     []string
+}
+
+// CreateCrawler returns a Crawler for `client` which fetches up to 200 resources no
+// more than 3 links from the entry point, sampling one member of each collection
+func CreateCrawler(client *Client) *Crawler {
+	return &Crawler{
+		Client:      client,
+		MaxDepth:    3,
+		MaxNodes:    200,
+		SampleItems: 1,
+		SkipRels:    []string{"next", "previous", "prev", "first", "last"},
+	}
+}
+
+func (c *Crawler) skipRel(rel string) bool {
+	for _, skip := range c.SkipRels {
+		if rel == skip {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Crawler) allowedHost(href string, entry *url.URL) bool {
+	u, err := url.Parse(href)
+	if err != nil {
+		return false
+	}
+	if len(c.AllowedHosts) == 0 {
+		return u.Host == entry.Host
+	}
+	for _, host := range c.AllowedHosts {
+		if strings.EqualFold(u.Host, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// crawlKey identifies a resource regardless of any fragment
+func crawlKey(href string) string {
+	if i := strings.IndexByte(href, '#'); i >= 0 {
+		return href[:i]
+	}
+	return href
+}
+
+// collectionItems returns the members of a collection document, whether as deviceserver
+// "Items" or arrays within HAL "_embedded"
+func collectionItems(doc []byte) []json.RawMessage {
+	var collection struct {
+		Items    []json.RawMessage          `json:"Items"`
+		Embedded map[string]json.RawMessage `json:"_embedded"`
+	}
+	if json.Unmarshal(doc, &collection) != nil {
+		return nil
+	}
+	items := collection.Items
+	for _, embedded := range collection.Embedded {
+		var members []json.RawMessage
+		if json.Unmarshal(embedded, &members) == nil {
+			items = append(items, members...)
+		}
+	}
+	return items
+}
+
+// Crawl maps the API, stopping early only if `ctx` is done. Failing resources are
+// recorded in the graph rather than ending the crawl.
+func (c *Crawler) Crawl(ctx context.Context) (*CrawlGraph, error) {
+	entry, err := url.Parse(c.Client.EntryURL)
+	if err != nil {
+		return nil, err
+	}
+	graph := &CrawlGraph{Entry: c.Client.EntryURL}
+	index := map[string]int{}
+	var queue []int
+
+	// add records a node the first time it is seen, queueing it if it can be fetched
+	add := func(href string, depth int, templated bool) {
+		key := crawlKey(href)
+		if _, seen := index[key]; seen {
+			return
+		}
+		node := CrawlNode{URL: key, Depth: depth}
+		switch {
+		case templated:
+			node.Skipped = "templated"
+		case !c.allowedHost(key, entry):
+			node.Skipped = "host"
+		case depth > c.MaxDepth:
+			node.Skipped = "depth"
+		case c.MaxNodes > 0 && len(queue) >= c.MaxNodes:
+			node.Skipped = "limit"
+		}
+		index[key] = len(graph.Nodes)
+		graph.Nodes = append(graph.Nodes, node)
+		if node.Skipped == "" {
+			queue = append(queue, index[key])
+		}
+	}
+
+	add(c.Client.EntryURL, 0, false)
+	for next := 0; next < len(queue); next++ {
+		if err := ctx.Err(); err != nil {
+			return graph, err
+		}
+		node := &graph.Nodes[queue[next]]
+		from, depth := node.URL, node.Depth
+
+		var doc json.RawMessage
+		resp, err := c.Client.GetContext(ctx, from, nil, nil, nil, &doc)
+		if resp != nil {
+			node.StatusCode = resp.StatusCode
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return graph, ctx.Err()
+			}
+			var herr *HTTPError
+			if !errors.As(err, &herr) {
+				node.Error = err.Error()
+			}
+			continue
+		}
+		base := from
+		if resp.Request != nil && resp.Request.URL != nil {
+			base = resp.Request.URL.String()
+		}
+
+		links, _ := c.Client.dialect().ExtractLinks(doc)
+		for _, link := range links {
+			if link.Rel == "self" || c.skipRel(link.Rel) {
+				continue
+			}
+			to := crawlKey(ResolveHref(base, link.Href))
+			graph.Edges = append(graph.Edges, CrawlEdge{From: from, To: to, Rel: link.Rel})
+			add(to, depth+1, link.Templated || strings.Contains(link.Href, "{"))
+		}
+
+		items := collectionItems(doc)
+		if len(items) > c.SampleItems {
+			items = items[:c.SampleItems]
+		}
+		for _, item := range items {
+			itemLinks, _ := c.Client.dialect().ExtractLinks(item)
+			self, err := itemLinks.Get("self")
+			if err != nil {
+				continue
+			}
+			to := crawlKey(ResolveHref(base, self.Href))
+			graph.Edges = append(graph.Edges, CrawlEdge{From: from, To: to, Rel: "item", Item: true})
+			add(to, depth+1, false)
+		}
+	}
+	return graph, nil
+}
+
+// WriteJSON writes the graph as indented JSON
+func (g *CrawlGraph) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(g)
+}
+
+// WriteDOT writes the graph in Graphviz DOT format, with each edge labelled by its rel.
+// Nodes which were not fetched are dashed, and failed ones red.
+func (g *CrawlGraph) WriteDOT(w io.Writer) error {
+	ids := map[string]string{}
+	_, err := fmt.Fprintln(w, "digraph api {\n  rankdir=LR;\n  node [shape=box];")
+	if err != nil {
+		return err
+	}
+	for i, node := range g.Nodes {
+		ids[node.URL] = fmt.Sprintf("n%d", i)
+		label := node.URL
+		if node.StatusCode != 0 {
+			label = fmt.Sprintf("%s\n%d", label, node.StatusCode)
+		}
+		attrs := ""
+		switch {
+		case node.Skipped != "":
+			label = fmt.Sprintf("%s\n(%s)", label, node.Skipped)
+			attrs = ", style=dashed"
+		case node.Error != "" || node.StatusCode >= 400:
+			attrs = ", color=red"
+		}
+		_, err = fmt.Fprintf(w, "  n%d [label=%q%s];\n", i, label, attrs)
+		if err != nil {
+			return err
+		}
+	}
+	for _, edge := range g.Edges {
+		attrs := ""
+		if edge.Item {
+			attrs = ", style=dotted"
+		}
+		_, err = fmt.Fprintf(w, "  %s -> %s [label=%q%s];\n", ids[edge.From], ids[edge.To], edge.Rel, attrs)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintln(w, "}")
+	return err
+}
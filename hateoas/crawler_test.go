@@ -0,0 +1,85 @@
+package hateoas
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCrawler(t *testing.T) {
+	assert := assert.New(t)
+
+	hits := map[string]int{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits[r.URL.Path]++
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte(`{"Links":[
+				{"rel":"self","href":"/"},
+				{"rel":"clients","href":"/clients"},
+				{"rel":"accesskeys","href":"/accesskeys"},
+				{"rel":"client","href":"/clients/{id}","templated":true},
+				{"rel":"docs","href":"http://elsewhere.example/docs"}]}`))
+		case "/clients":
+			w.Write([]byte(`{"Links":[{"rel":"root","href":"/"},{"rel":"next","href":"/clients?page=2"}],"Items":[
+				{"Links":[{"rel":"self","href":"/clients/1"}]},
+				{"Links":[{"rel":"self","href":"/clients/2"}]}]}`))
+		case "/clients/1":
+			w.Write([]byte(`{"Links":[{"rel":"objecttypes","href":"/clients/1/objecttypes"}]}`))
+		case "/clients/1/objecttypes":
+			w.Write([]byte(`{"Links":[{"rel":"deeper","href":"/clients/1/objecttypes/deeper"}]}`))
+		case "/accesskeys":
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer ts.Close()
+
+	crawler := CreateCrawler(Create(&Client{EntryURL: ts.URL + "/"}))
+	graph, err := crawler.Crawl(context.Background())
+	assert.Nil(err)
+
+	nodes := map[string]CrawlNode{}
+	for _, node := range graph.Nodes {
+		nodes[node.URL] = node
+	}
+	assert.Equal(http.StatusOK, nodes[ts.URL+"/"].StatusCode)
+	assert.Equal(http.StatusForbidden, nodes[ts.URL+"/accesskeys"].StatusCode)
+	assert.Equal("templated", nodes[ts.URL+"/clients/{id}"].Skipped) // templates are not followed until expanded
+	assert.Equal("host", nodes["http://elsewhere.example/docs"].Skipped)
+	assert.Equal(3, nodes[ts.URL+"/clients/1/objecttypes"].Depth)
+	assert.Equal(http.StatusOK, nodes[ts.URL+"/clients/1/objecttypes"].StatusCode)
+	assert.Equal("depth", nodes[ts.URL+"/clients/1/objecttypes/deeper"].Skipped)
+	assert.NotContains(nodes, ts.URL+"/clients/2")      // only one item sampled
+	assert.NotContains(nodes, ts.URL+"/clients?page=2") // paging isn't followed
+	assert.Contains(graph.Edges, CrawlEdge{From: ts.URL + "/clients", To: ts.URL + "/", Rel: "root"})
+	assert.Contains(graph.Edges, CrawlEdge{From: ts.URL + "/clients", To: ts.URL + "/clients/1", Rel: "item", Item: true})
+	assert.Equal(1, hits["/"]) // the cycle back to the root isn't walked again
+
+	crawler.MaxDepth = 4
+	graph, err = crawler.Crawl(context.Background())
+	assert.Nil(err)
+	assert.Equal(1, hits["/clients/1/objecttypes/deeper"])
+
+	var buf bytes.Buffer
+	assert.Nil(graph.WriteJSON(&buf))
+	var decoded CrawlGraph
+	assert.Nil(json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(graph, &decoded)
+
+	buf.Reset()
+	assert.Nil(graph.WriteDOT(&buf))
+	assert.Contains(buf.String(), "digraph api {")
+	assert.Contains(buf.String(), `[label="clients"];`)
+	assert.Contains(buf.String(), `[label="item", style=dotted];`)
+	assert.Contains(buf.String(), `color=red`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = crawler.Crawl(ctx)
+	assert.Equal(context.Canceled, err)
+}
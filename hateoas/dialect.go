@@ -0,0 +1,214 @@
+package hateoas
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// LinkExtractor pulls the links out of a response body, which lets Navigate
+// work against different hypermedia formats. Relative hrefs are fine, the
+// Client resolves them against the document URL.
+type LinkExtractor interface {
+	ExtractLinks(body []byte) (Links, error)
+}
+
+// DeviceServerDialect is the default, a top level "Links" array of {rel, href, type}
+type DeviceServerDialect struct{}
+
+// ExtractLinks implements LinkExtractor
+func (DeviceServerDialect) ExtractLinks(body []byte) (Links, error) {
+	var ep SimpleEndpoint
+	err := json.Unmarshal(body, &ep)
+	if err != nil {
+		return nil, err
+	}
+	return ep.Links, nil
+}
+
+// HALDialect reads HAL "_links", where each rel maps to a link object or an array of them
+type HALDialect struct{}
+
+type halLink struct {
+	Href      string `json:"href"`
+	Type      string `json:"type"`
+	Templated bool   `json:"templated"`
+}
+
+// halLinks decodes a "_links" object, returning rels in a stable order
+func halLinks(raw map[string]json.RawMessage) Links {
+	rels := make([]string, 0, len(raw))
+	for rel := range raw {
+		if rel != "curies" {
+			rels = append(rels, rel)
+		}
+	}
+	sort.Strings(rels)
+
+	links := Links{}
+	for _, rel := range rels {
+		var many []halLink
+		if json.Unmarshal(raw[rel], &many) != nil {
+			var one halLink
+			if json.Unmarshal(raw[rel], &one) != nil {
+				continue
+			}
+			many = []halLink{one}
+		}
+		for _, l := range many {
+			links = append(links, Link{Rel: rel, Href: l.Href, Type: l.Type, Templated: l.Templated})
+		}
+	}
+	return links
+}
+
+// ExtractLinks implements LinkExtractor
+func (HALDialect) ExtractLinks(body []byte) (Links, error) {
+	var doc struct {
+		Links map[string]json.RawMessage `json:"_links"`
+	}
+	err := json.Unmarshal(body, &doc)
+	if err != nil {
+		return nil, err
+	}
+	return halLinks(doc.Links), nil
+}
+
+// SirenDialect reads Siren "links", sub-entities (both embedded links and the self
+// link of embedded representations) and "actions", the latter keyed by action name
+type SirenDialect struct{}
+
+type sirenLink struct {
+	Rel  []string `json:"rel"`
+	Href string   `json:"href"`
+	Type string   `json:"type"`
+}
+
+type sirenEntity struct {
+	Rel      []string      `json:"rel"`
+	Href     string        `json:"href"`
+	Type     string        `json:"type"`
+	Links    []sirenLink   `json:"links"`
+	Entities []sirenEntity `json:"entities"`
+	Actions  []struct {
+		Name string `json:"name"`
+		Href string `json:"href"`
+		Type string `json:"type"`
+	} `json:"actions"`
+}
+
+// ExtractLinks implements LinkExtractor
+func (SirenDialect) ExtractLinks(body []byte) (Links, error) {
+	var doc sirenEntity
+	err := json.Unmarshal(body, &doc)
+	if err != nil {
+		return nil, err
+	}
+
+	links := Links{}
+	for _, l := range doc.Links {
+		for _, rel := range l.Rel {
+			links = append(links, Link{Rel: rel, Href: l.Href, Type: l.Type})
+		}
+	}
+	for _, e := range doc.Entities {
+		href := e.Href
+		if href == "" {
+			// embedded representation, so use its own self link
+			for _, l := range e.Links {
+				for _, rel := range l.Rel {
+					if rel == "self" && href == "" {
+						href = l.Href
+					}
+				}
+			}
+		}
+		if href == "" {
+			continue
+		}
+		for _, rel := range e.Rel {
+			links = append(links, Link{Rel: rel, Href: href, Type: e.Type})
+		}
+	}
+	for _, a := range doc.Actions {
+		links = append(links, Link{Rel: a.Name, Href: a.Href, Type: a.Type})
+	}
+	return links, nil
+}
+
+// JSONAPIDialect reads JSON:API top level "links", plus the "links" and
+// relationship "related" links of a single primary resource (keyed by relationship name)
+type JSONAPIDialect struct{}
+
+// jsonAPILinks decodes a JSON:API links object, where each value is either a
+// string or a link object with "href"
+func jsonAPILinks(raw map[string]json.RawMessage) Links {
+	rels := make([]string, 0, len(raw))
+	for rel := range raw {
+		rels = append(rels, rel)
+	}
+	sort.Strings(rels)
+
+	links := Links{}
+	for _, rel := range rels {
+		var href string
+		if json.Unmarshal(raw[rel], &href) != nil {
+			var obj struct {
+				Href string `json:"href"`
+				Type string `json:"type"`
+			}
+			if json.Unmarshal(raw[rel], &obj) != nil {
+				continue
+			}
+			if obj.Href != "" {
+				links = append(links, Link{Rel: rel, Href: obj.Href, Type: obj.Type})
+			}
+			continue
+		}
+		if href != "" {
+			links = append(links, Link{Rel: rel, Href: href})
+		}
+	}
+	return links
+}
+
+// ExtractLinks implements LinkExtractor
+func (JSONAPIDialect) ExtractLinks(body []byte) (Links, error) {
+	var doc struct {
+		Links map[string]json.RawMessage `json:"links"`
+		Data  json.RawMessage            `json:"data"`
+	}
+	err := json.Unmarshal(body, &doc)
+	if err != nil {
+		return nil, err
+	}
+	links := jsonAPILinks(doc.Links)
+
+	// collections have no single resource to take links from
+	var resource struct {
+		Links         map[string]json.RawMessage `json:"links"`
+		Relationships map[string]struct {
+			Links map[string]json.RawMessage `json:"links"`
+		} `json:"relationships"`
+	}
+	if json.Unmarshal(doc.Data, &resource) != nil {
+		return links, nil
+	}
+	for _, l := range jsonAPILinks(resource.Links) {
+		if _, err := links.Get(l.Rel); err != nil {
+			links = append(links, l)
+		}
+	}
+	names := make([]string, 0, len(resource.Relationships))
+	for name := range resource.Relationships {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		related, err := jsonAPILinks(resource.Relationships[name].Links).Get("related")
+		if err != nil {
+			continue
+		}
+		links = append(links, Link{Rel: name, Href: related.Href, Type: related.Type})
+	}
+	return links, nil
+}
@@ -0,0 +1,126 @@
+package hateoas
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeviceServerDialect(t *testing.T) {
+	assert := assert.New(t)
+
+	links, err := DeviceServerDialect{}.ExtractLinks([]byte(`{"Links":[{"rel":"bob","href":"/bob","type":"application/json"}]}`))
+	assert.Nil(err)
+	assert.Equal(Links{{Rel: "bob", Href: "/bob", Type: "application/json"}}, links)
+
+	_, err = DeviceServerDialect{}.ExtractLinks([]byte(`not json`))
+	assert.NotNil(err)
+}
+
+func TestHALDialect(t *testing.T) {
+	assert := assert.New(t)
+
+	links, err := HALDialect{}.ExtractLinks([]byte(`{
+		"_links": {
+			"self": {"href": "/orders"},
+			"curies": [{"name": "ea", "href": "/docs/{rel}", "templated": true}],
+			"item": [{"href": "/orders/1"}, {"href": "/orders/2", "type": "application/hal+json"}],
+			"find": {"href": "/orders{?id}", "templated": true}
+		},
+		"total": 2
+	}`))
+	assert.Nil(err)
+	assert.Equal(Links{
+		{Rel: "find", Href: "/orders{?id}", Templated: true},
+		{Rel: "item", Href: "/orders/1"},
+		{Rel: "item", Href: "/orders/2", Type: "application/hal+json"},
+		{Rel: "self", Href: "/orders"},
+	}, links)
+}
+
+func TestSirenDialect(t *testing.T) {
+	assert := assert.New(t)
+
+	links, err := SirenDialect{}.ExtractLinks([]byte(`{
+		"class": ["order"],
+		"entities": [
+			{"class": ["items"], "rel": ["http://x.io/rels/order-items"], "href": "/orders/42/items"},
+			{"rel": ["customer"], "properties": {"id": "pj"}, "links": [{"rel": ["self"], "href": "/customers/pj"}]},
+			{"rel": ["orphan"], "properties": {}}
+		],
+		"actions": [{"name": "add-item", "method": "POST", "href": "/orders/42/items", "type": "application/x-www-form-urlencoded"}],
+		"links": [{"rel": ["self", "canonical"], "href": "/orders/42"}, {"rel": ["next"], "href": "/orders/43"}]
+	}`))
+	assert.Nil(err)
+	assert.Equal(Links{
+		{Rel: "self", Href: "/orders/42"},
+		{Rel: "canonical", Href: "/orders/42"},
+		{Rel: "next", Href: "/orders/43"},
+		{Rel: "http://x.io/rels/order-items", Href: "/orders/42/items"},
+		{Rel: "customer", Href: "/customers/pj"},
+		{Rel: "add-item", Href: "/orders/42/items", Type: "application/x-www-form-urlencoded"},
+	}, links)
+}
+
+func TestJSONAPIDialect(t *testing.T) {
+	assert := assert.New(t)
+
+	links, err := JSONAPIDialect{}.ExtractLinks([]byte(`{
+		"links": {"self": "/articles/1", "next": {"href": "/articles/2"}, "prev": null},
+		"data": {
+			"type": "articles", "id": "1",
+			"links": {"self": "/articles/1", "edit": "/articles/1/edit"},
+			"relationships": {
+				"author": {"links": {"self": "/articles/1/relationships/author", "related": "/articles/1/author"}},
+				"comments": {"links": {"related": {"href": "/articles/1/comments"}}}
+			}
+		}
+	}`))
+	assert.Nil(err)
+	assert.Equal(Links{
+		{Rel: "next", Href: "/articles/2"},
+		{Rel: "self", Href: "/articles/1"},
+		{Rel: "edit", Href: "/articles/1/edit"},
+		{Rel: "author", Href: "/articles/1/author"},
+		{Rel: "comments", Href: "/articles/1/comments"},
+	}, links)
+
+	// collections have no resource links
+	links, err = JSONAPIDialect{}.ExtractLinks([]byte(`{"links": {"self": "/articles"}, "data": [{"id": "1"}]}`))
+	assert.Nil(err)
+	assert.Equal(Links{{Rel: "self", Href: "/articles"}}, links)
+}
+
+func TestHALNavigate(t *testing.T) {
+	assert := assert.New(t)
+
+	hs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `{"_links":{"self":{"href":"/"},"bob":{"href":"/bob"}}}`)
+		case "/bob":
+			fmt.Fprint(w, `{"Name":"bob","_links":{"where":{"href":"where"}}}`)
+		case "/where":
+			fmt.Fprint(w, `{"Where":"over the rainbow"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer hs.Close()
+
+	client := Create(&Client{
+		EntryURL: hs.URL,
+		Dialect:  HALDialect{},
+	})
+
+	var where Where
+	_, err := client.Get("", Navigate{"bob", "where"}, nil, nil, &where)
+	assert.Nil(err)
+	assert.Equal("over the rainbow", where.Where)
+
+	_, err = client.Get("", Navigate{"bob", "nowhere"}, nil, nil, &where)
+	assert.True(IsLinkNotFound(err))
+}
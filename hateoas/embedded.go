@@ -0,0 +1,105 @@
+package hateoas
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+)
+
+// EmbeddedExtractor is optionally implemented by a LinkExtractor which can find the
+// representation a link points at already embedded in the document, letting
+// navigation skip the GET for that hop. `link` is as returned by ExtractLinks.
+type EmbeddedExtractor interface {
+	ExtractEmbedded(body []byte, link Link) (json.RawMessage, bool)
+}
+
+// embeddedResponse fakes up the response to `req` for an embedded representation
+func embeddedResponse(req *http.Request, body []byte) *http.Response {
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// ExtractEmbedded implements EmbeddedExtractor, finding an entry of a collection's
+// "Items" whose "self" link is the link being followed
+func (DeviceServerDialect) ExtractEmbedded(body []byte, link Link) (json.RawMessage, bool) {
+	var collection struct {
+		Items []json.RawMessage `json:"Items"`
+	}
+	if json.Unmarshal(body, &collection) != nil {
+		return nil, false
+	}
+	for _, item := range collection.Items {
+		var ep SimpleEndpoint
+		if json.Unmarshal(item, &ep) != nil {
+			continue
+		}
+		self, err := ep.Links.Get("self")
+		if err == nil && self.Href == link.Href {
+			return item, true
+		}
+	}
+	return nil, false
+}
+
+// ExtractEmbedded implements EmbeddedExtractor using HAL "_embedded". Where a rel
+// has several embedded resources, the one whose self link matches is used.
+func (HALDialect) ExtractEmbedded(body []byte, link Link) (json.RawMessage, bool) {
+	var doc struct {
+		Embedded map[string]json.RawMessage `json:"_embedded"`
+	}
+	if json.Unmarshal(body, &doc) != nil {
+		return nil, false
+	}
+	raw, exists := doc.Embedded[link.Rel]
+	if !exists {
+		return nil, false
+	}
+	var many []json.RawMessage
+	if json.Unmarshal(raw, &many) != nil {
+		return raw, true
+	}
+	for _, resource := range many {
+		links, err := HALDialect{}.ExtractLinks(resource)
+		if err != nil {
+			continue
+		}
+		self, err := links.Get("self")
+		if err == nil && self.Href == link.Href {
+			return resource, true
+		}
+	}
+	return nil, false
+}
+
+// ExtractEmbedded implements EmbeddedExtractor using Siren sub-entities which are
+// embedded representations rather than embedded links
+func (SirenDialect) ExtractEmbedded(body []byte, link Link) (json.RawMessage, bool) {
+	var doc struct {
+		Entities []json.RawMessage `json:"entities"`
+	}
+	if json.Unmarshal(body, &doc) != nil {
+		return nil, false
+	}
+	for _, raw := range doc.Entities {
+		var e sirenEntity
+		if json.Unmarshal(raw, &e) != nil || e.Href != "" {
+			continue
+		}
+		for _, rel := range e.Rel {
+			if rel == link.Rel {
+				return raw, true
+			}
+		}
+	}
+	return nil, false
+}
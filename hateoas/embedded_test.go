@@ -0,0 +1,104 @@
+package hateoas
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmbeddedHAL(t *testing.T) {
+	assert := assert.New(t)
+
+	hits := map[string]int{}
+	hs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits[r.URL.Path]++
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `{"_links":{"bob":{"href":"/bob"}},"_embedded":{"bob":{"Name":"embedded bob","_links":{"self":{"href":"/bob"},"where":{"href":"/where"}}}}}`)
+		case "/bob":
+			fmt.Fprint(w, `{"Name":"bob","_links":{"self":{"href":"/bob"},"where":{"href":"/where"}}}`)
+		case "/where":
+			fmt.Fprint(w, `{"Where":"over the rainbow"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer hs.Close()
+
+	client := Create(&Client{
+		EntryURL: hs.URL,
+		Dialect:  HALDialect{},
+	})
+
+	var where Where
+	_, err := client.Get("", Navigate{"bob", "where"}, nil, nil, &where)
+	assert.Nil(err)
+	assert.Equal("over the rainbow", where.Where)
+	assert.Equal(map[string]int{"/": 1, "/where": 1}, hits)
+
+	// the terminal GET can be satisfied too
+	var bob Bob
+	resp, err := client.Get("", Navigate{"bob"}, nil, nil, &bob)
+	assert.Nil(err)
+	assert.Equal("embedded bob", bob.Name)
+	assert.Equal(hs.URL+"/bob", resp.Request.URL.String())
+	assert.Equal(0, hits["/bob"])
+
+	_, err = client.Get("", Navigate{"bob"}, nil, nil, &bob, WithForceFetch())
+	assert.Nil(err)
+	assert.Equal("bob", bob.Name)
+	assert.Equal(1, hits["/bob"])
+}
+
+func TestEmbeddedDeviceServer(t *testing.T) {
+	assert := assert.New(t)
+
+	hits := map[string]int{}
+	var ds *httptest.Server
+	ds = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits[r.URL.Path]++
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprintf(w, `{"Links":[{"rel":"bob","href":"%s/people/bob"}],"Items":[
+				{"Name":"alice","Links":[{"rel":"self","href":"%s/people/alice"}]},
+				{"Name":"bob","Links":[{"rel":"self","href":"%s/people/bob"},{"rel":"where","href":"%s/where"}]}]}`,
+				ds.URL, ds.URL, ds.URL, ds.URL)
+		case "/where":
+			fmt.Fprint(w, `{"Where":"over the rainbow"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ds.Close()
+
+	client := Create(&Client{
+		EntryURL: ds.URL,
+	})
+
+	var where Where
+	_, err := client.Get("", Navigate{"bob", "where"}, nil, nil, &where)
+	assert.Nil(err)
+	assert.Equal("over the rainbow", where.Where)
+	assert.Equal(map[string]int{"/": 1, "/where": 1}, hits)
+
+	// only GET can be satisfied from an embedded representation
+	_, err = client.Delete("", Navigate{"bob"}, nil, nil, nil)
+	assert.True(IsNotFound(err))
+	assert.Equal(1, hits["/people/bob"])
+}
+
+func TestEmbeddedSiren(t *testing.T) {
+	assert := assert.New(t)
+
+	raw, ok := SirenDialect{}.ExtractEmbedded([]byte(`{"entities":[
+		{"rel":["customer"],"href":"/customers/pj"},
+		{"rel":["customer"],"properties":{"id":"pj"}}]}`), Link{Rel: "customer"})
+	assert.True(ok)
+	assert.JSONEq(`{"rel":["customer"],"properties":{"id":"pj"}}`, string(raw))
+
+	_, ok = SirenDialect{}.ExtractEmbedded([]byte(`{"entities":[]}`), Link{Rel: "customer"})
+	assert.False(ok)
+}
@@ -0,0 +1,107 @@
+package hateoas
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// HTTPError is returned by Client.Do whenever a request comes back with a
+// HTTP status >= 400. The response body is kept so that callers can decode
+// any error payload the server sent.
+type HTTPError struct {
+	StatusCode int
+	Method     string
+	URL        string
+	Body       []byte
+}
+
+// Error keeps the ErrorHttpStatus prefix so that existing string checks still work
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("%s: %s %s returned %d", ErrorHttpStatus, e.Method, e.URL, e.StatusCode)
+}
+
+// Decode unmarshals the response body into `v`
+func (e *HTTPError) Decode(v interface{}) error {
+	if len(e.Body) == 0 {
+		return errors.New("empty response body")
+	}
+	return json.Unmarshal(e.Body, v)
+}
+
+// IsStatus reports whether `err` is (or wraps) a HTTPError with the given status code
+func IsStatus(err error, statusCode int) bool {
+	var herr *HTTPError
+	if !errors.As(err, &herr) {
+		return false
+	}
+	return herr.StatusCode == statusCode
+}
+
+// IsBadRequest reports whether `err` is a HTTP 400 error
+func IsBadRequest(err error) bool {
+	return IsStatus(err, http.StatusBadRequest)
+}
+
+// IsUnauthorized reports whether `err` is a HTTP 401 error
+func IsUnauthorized(err error) bool {
+	return IsStatus(err, http.StatusUnauthorized)
+}
+
+// IsForbidden reports whether `err` is a HTTP 403 error
+func IsForbidden(err error) bool {
+	return IsStatus(err, http.StatusForbidden)
+}
+
+// IsNotFound reports whether `err` is a HTTP 404 error
+func IsNotFound(err error) bool {
+	return IsStatus(err, http.StatusNotFound)
+}
+
+// IsConflict reports whether `err` is a HTTP 409 error
+func IsConflict(err error) bool {
+	return IsStatus(err, http.StatusConflict)
+}
+
+// IsGone reports whether `err` is a HTTP 410 error
+func IsGone(err error) bool {
+	return IsStatus(err, http.StatusGone)
+}
+
+// IsServerError reports whether `err` is any HTTP 5xx error
+func IsServerError(err error) bool {
+	var herr *HTTPError
+	if !errors.As(err, &herr) {
+		return false
+	}
+	return herr.StatusCode >= http.StatusInternalServerError
+}
+
+// LinkNotFoundError is returned by Client.Do when a rel to be navigated is missing, and
+// by Links.Get and Links.GetType. Error() starts with ErrorLinkNotFound, but match it
+// with IsLinkNotFound.
+type LinkNotFoundError struct {
+	Rel string
+	// Hop is the index into the Navigate of the missing rel
+	Hop int
+	// URL is the document which lacked the rel, empty outside of navigation
+	URL string
+	// Available are the rels that document did have
+	Available []string
+}
+
+// Error describes where the link was missing, and what was there instead
+func (e *LinkNotFoundError) Error() string {
+	if e.URL == "" {
+		return fmt.Sprintf("%s: %q, available: %v", ErrorLinkNotFound, e.Rel, e.Available)
+	}
+	return fmt.Sprintf("%s: %q (hop %d) at %s, available: %v", ErrorLinkNotFound, e.Rel, e.Hop, e.URL, e.Available)
+}
+
+// IsLinkNotFound reports whether `err` is (or wraps) a LinkNotFoundError
+func IsLinkNotFound(err error) bool {
+	var lerr *LinkNotFoundError
+	return errors.As(err, &lerr)
+}
@@ -0,0 +1,48 @@
+package hateoas
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPError(t *testing.T) {
+	assert := assert.New(t)
+
+	es := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"Where":"nowhere"}`)
+	}))
+	defer es.Close()
+
+	client := Create(&Client{
+		EntryURL: es.URL,
+	})
+
+	_, err := client.Get("", nil, nil, nil, nil)
+	assert.NotNil(err)
+	assert.True(IsNotFound(err))
+	assert.False(IsConflict(err))
+	assert.False(IsServerError(err))
+	assert.Contains(err.Error(), ErrorHttpStatus)
+
+	var herr *HTTPError
+	assert.True(errors.As(errors.Wrap(err, "wrapped"), &herr))
+	assert.Equal(http.StatusNotFound, herr.StatusCode)
+	assert.Equal("GET", herr.Method)
+	assert.Equal(es.URL, herr.URL)
+
+	var where Where
+	assert.Nil(herr.Decode(&where))
+	assert.Equal("nowhere", where.Where)
+
+	// errors on intermediate hops are reported the same way
+	_, err = client.Get("", Navigate{"bob"}, nil, nil, nil)
+	assert.True(IsNotFound(err))
+
+	assert.False(IsNotFound(errors.New(ErrorLinkNotFound)))
+}
@@ -0,0 +1,44 @@
+package hateoas
+
+import (
+	"context"
+	"net/http"
+)
+
+// Follow GETs `link` into `result`, asking for the link's media type (if it has one)
+// with the Accept header. Templated links are expanded with the variables given by
+// WithTemplateVars for the link's rel.
+func (c *Client) Follow(link Link, result interface{}, opts ...RequestOption) (*http.Response, error) {
+	return c.FollowContext(context.Background(), link, result, opts...)
+}
+
+// FollowContext is the same as Follow, bound to `ctx`
+func (c *Client) FollowContext(ctx context.Context, link Link, result interface{}, opts ...RequestOption) (*http.Response, error) {
+	href := link.Href
+	o := collectOptions(opts)
+	if vars, ok := o.templateVars[link.Rel]; link.Templated || ok {
+		expanded, err := link.Expand(vars)
+		if err != nil {
+			return nil, err
+		}
+		href = expanded
+	}
+
+	var headers Headers
+	if link.Type != "" {
+		headers = Headers{"Accept": link.Type}
+	}
+	return c.DoContext(ctx, "GET", href, nil, headers, nil, result, opts...)
+}
+
+// FollowAs is Client.FollowContext decoding into a new T, e.g.
+//
+//	key, err := hateoas.FollowAs[AccessKey](ctx, client, link)
+func FollowAs[T any](ctx context.Context, c *Client, link Link, opts ...RequestOption) (*T, error) {
+	var result T
+	_, err := c.FollowContext(ctx, link, &result, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
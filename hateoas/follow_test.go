@@ -0,0 +1,66 @@
+package hateoas
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLinksHelpers(t *testing.T) {
+	assert := assert.New(t)
+
+	links := Links{
+		{Rel: "self", Href: "/things/1"},
+		{Rel: "alternate", Href: "/things/1.xml", Type: "application/xml"},
+		{Rel: "alternate", Href: "/things/1.json", Type: "application/json"},
+	}
+
+	assert.Len(links.GetAll("alternate"), 2)
+	assert.Len(links.GetAll("missing"), 0)
+	assert.True(links.Has("self"))
+	assert.False(links.Has("missing"))
+
+	link, err := links.GetType("alternate", "Application/JSON; charset=utf-8")
+	assert.Nil(err)
+	assert.Equal("/things/1.json", link.Href)
+	_, err = links.GetType("alternate", "text/html")
+	assert.True(IsLinkNotFound(err))
+
+	href, err := links.SelfHref()
+	assert.Nil(err)
+	assert.Equal("/things/1", href)
+	_, err = links[1:].SelfHref()
+	assert.NotNil(err)
+}
+
+func TestFollow(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"Accept":%q,"Path":%q}`, r.Header.Get("Accept"), r.URL.Path)
+	}))
+	defer ts.Close()
+
+	type echo struct {
+		Accept string
+		Path   string
+	}
+	c := Create(&Client{EntryURL: ts.URL})
+
+	var e echo
+	_, err := c.Follow(Link{Rel: "thing", Href: ts.URL + "/things/1", Type: "application/vnd.thing+json"}, &e)
+	assert.Nil(err)
+	assert.Equal("application/vnd.thing+json", e.Accept)
+	assert.Equal("/things/1", e.Path)
+
+	typed, err := FollowAs[echo](context.Background(), c,
+		Link{Rel: "thing", Href: ts.URL + "/things/{id}", Templated: true},
+		WithTemplateVars("thing", TemplateVars{"id": "2"}))
+	assert.Nil(err)
+	assert.Equal("application/json", typed.Accept)
+	assert.Equal("/things/2", typed.Path)
+}
@@ -2,18 +2,24 @@
 package hateoas
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"mime"
 	"net/http"
 	"net/url"
+	"reflect"
 	"strings"
-
-	"github.com/pkg/errors"
+	"sync"
 )
 
 var (
+	// ErrorLinkNotFound starts the message of a LinkNotFoundError, which goes on to say which
+	// rel was missing and where. Comparing err.Error() (or errors.Cause(err).Error()) with it
+	// no longer matches, use IsLinkNotFound instead.
 	ErrorLinkNotFound = "Link not found"
 	ErrorHttpStatus   = "HTTP status error"
 	ErrorBadConfig    = "bad config"
@@ -24,6 +30,9 @@ type Link struct {
 	Rel  string `json:"rel"`
 	Href string `json:"href"`
 	Type string `json:"type"`
+
+	// Templated marks Href as a RFC 6570 URI template, see Expand
+	Templated bool `json:"templated,omitempty"`
 }
 
 // Links is just an array of Link, but with some helper methods
@@ -36,7 +45,52 @@ func (l Links) Get(rel string) (*Link, error) {
 			return &link, nil
 		}
 	}
-	return nil, errors.New(ErrorLinkNotFound)
+	return nil, &LinkNotFoundError{Rel: rel, Available: l.Rels()}
+}
+
+// GetAll returns every link matching the specified `rel` name, in order
+func (l Links) GetAll(rel string) Links {
+	var links Links
+	for _, link := range l {
+		if link.Rel == rel {
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+// Has reports whether there is a link with the specified `rel` name
+func (l Links) Has(rel string) bool {
+	_, err := l.Get(rel)
+	return err == nil
+}
+
+// GetType returns the first link matching `rel` whose media type is `mediaType`. Media
+// types are compared case-insensitively and ignoring parameters such as charset.
+func (l Links) GetType(rel string, mediaType string) (*Link, error) {
+	want := baseMediaType(mediaType)
+	for _, link := range l {
+		if link.Rel == rel && baseMediaType(link.Type) == want {
+			return &link, nil
+		}
+	}
+	return nil, &LinkNotFoundError{Rel: rel, Available: l.Rels()}
+}
+
+func baseMediaType(mediaType string) string {
+	if parsed, _, err := mime.ParseMediaType(mediaType); err == nil {
+		return parsed
+	}
+	return strings.ToLower(strings.TrimSpace(mediaType))
+}
+
+// SelfHref is Get("self") for when a missing link matters, unlike Self
+func (l Links) SelfHref() (string, error) {
+	link, err := l.Get("self")
+	if err != nil {
+		return "", err
+	}
+	return link.Href, nil
 }
 
 // Self is a small wrapper around Get, mostly useful when you don't need to worry if the link isn't actually there
@@ -49,6 +103,15 @@ func (l Links) Self() string {
 	return link.Href
 }
 
+// Rels returns the rel of every link, in order
+func (l Links) Rels() []string {
+	rels := make([]string, 0, len(l))
+	for _, link := range l {
+		rels = append(rels, link.Rel)
+	}
+	return rels
+}
+
 func (l Links) String() string {
 	s := "["
 	for i, _ := range l {
@@ -76,11 +139,26 @@ type HTTPDoer interface {
 	Do(*http.Request) (*http.Response, error)
 }
 
-// Client is the main object for executing requests
+// Client is the main object for executing requests. It is safe for concurrent use,
+// provided that DefaultHeaders is only modified (once the Client is in use) via
+// SetDefaultHeader/DelDefaultHeader. Use Clone for a copy with its own headers.
 type Client struct {
 	EntryURL       string
 	DefaultHeaders Headers
 	Http           HTTPDoer
+
+	// LinkCache is optional, and when set is used to skip the intermediate GETs
+	// of any `navigateLinks` that have been resolved before
+	LinkCache *LinkCache
+
+	// Dialect extracts links from each document whilst navigating. It defaults
+	// to DeviceServerDialect but HALDialect, SirenDialect and JSONAPIDialect are
+	// also available.
+	Dialect LinkExtractor
+
+	// mu guards DefaultHeaders, which is copied on write so that
+	// requests can range over a snapshot without holding the lock
+	mu sync.RWMutex
 }
 
 // Create will populate some defaults into a provided Client structure
@@ -92,6 +170,9 @@ func Create(config *Client) *Client {
 	if client.Http == nil {
 		client.Http = &http.Client{}
 	}
+	if client.Dialect == nil {
+		client.Dialect = DeviceServerDialect{}
+	}
 	if client.DefaultHeaders == nil {
 		client.DefaultHeaders = Headers{
 			"Accept":       "application/json",
@@ -101,34 +182,168 @@ func Create(config *Client) *Client {
 	return client
 }
 
+// SetDefaultHeader sets a header to be sent with every request
+func (c *Client) SetDefaultHeader(name string, value string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	headers := make(Headers, len(c.DefaultHeaders)+1)
+	for n, v := range c.DefaultHeaders {
+		headers[n] = v
+	}
+	headers[name] = value
+	c.DefaultHeaders = headers
+}
+
+// DelDefaultHeader stops a header being sent with every request
+func (c *Client) DelDefaultHeader(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	headers := make(Headers, len(c.DefaultHeaders))
+	for n, v := range c.DefaultHeaders {
+		if n != name {
+			headers[n] = v
+		}
+	}
+	c.DefaultHeaders = headers
+}
+
+// defaultHeaders returns the current headers, which must not be modified
+func (c *Client) defaultHeaders() Headers {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.DefaultHeaders
+}
+
+// Clone returns a copy of the Client with its own DefaultHeaders, e.g. for a goroutine
+// that needs different credentials. The HTTPDoer, LinkCache and Dialect are shared.
+func (c *Client) Clone() *Client {
+	headers := Headers{}
+	for n, v := range c.defaultHeaders() {
+		headers[n] = v
+	}
+	return &Client{
+		EntryURL:       c.EntryURL,
+		DefaultHeaders: headers,
+		Http:           c.Http,
+		LinkCache:      c.LinkCache,
+		Dialect:        c.Dialect,
+	}
+}
+
+func (c *Client) dialect() LinkExtractor {
+	if c.Dialect == nil {
+		return DeviceServerDialect{}
+	}
+	return c.Dialect
+}
+
 // Do will start at the provided URL (or default to `Client.EntryURL`) and traverse the links specified by `navigateLinks` (with GET)
-// before finally issuing `method` to the resultant URL
-func (c *Client) Do(method string, url string, navigateLinks Navigate, headers Headers, body io.Reader, result interface{}) (*http.Response, error) {
+// before finally issuing `method` to the resultant URL. Any `opts` tweak just this call, see RequestOption
+func (c *Client) Do(method string, url string, navigateLinks Navigate, headers Headers, body io.Reader, result interface{}, opts ...RequestOption) (*http.Response, error) {
+	return c.DoContext(context.Background(), method, url, navigateLinks, headers, body, result, opts...)
+}
+
+// DoContext is the same as Do, but every request issued (including each intermediate GET
+// whilst traversing `navigateLinks`) is bound to `ctx`, so cancelling it stops the traversal
+func (c *Client) DoContext(ctx context.Context, method string, url string, navigateLinks Navigate, headers Headers, body io.Reader, result interface{}, opts ...RequestOption) (*http.Response, error) {
 	if url == "" {
 		url = c.EntryURL
 	}
-	for _, nav := range navigateLinks {
-		var ep SimpleEndpoint
-		resp, err := c.Get(url, nil, nil, nil, &ep)
+	o := collectOptions(opts)
+	start := url
+	resolved := 0
+	cacheable := true
+	if c.LinkCache != nil && len(navigateLinks) > 0 {
+		resolved, url = c.LinkCache.lookup(start, navigateLinks)
+		if resolved == 0 {
+			url = start
+		}
+	}
+	if o.trace != nil {
+		*o.trace = Trace{FromCache: resolved}
+	}
+	// doc holds the current document when it is already in hand, i.e. embedded in its parent
+	var doc json.RawMessage
+	var resp *http.Response
+	for i := resolved; i < len(navigateLinks); i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		base := url
+		inHand := doc != nil
+		statusCode := 0
+		if !inHand {
+			var err error
+			resp, err = c.GetContext(ctx, url, nil, nil, nil, &doc)
+			if err != nil {
+				if resp != nil {
+					o.trace.hop(url, resp.StatusCode, false, nil)
+				}
+				return resp, err
+			}
+			statusCode = resp.StatusCode
+			if resp.Request != nil && resp.Request.URL != nil {
+				base = resp.Request.URL.String()
+			}
+		}
+		links, err := c.dialect().ExtractLinks(doc)
 		if err != nil {
 			return resp, err
 		}
-		if resp.StatusCode >= http.StatusBadRequest {
-			return resp, errors.New(ErrorHttpStatus)
-		}
-		link, err := ep.Links.Get(nav)
+		o.trace.hop(base, statusCode, inHand, links)
+		link, err := links.Get(navigateLinks[i])
 		if err != nil {
-			return resp, errors.New(ErrorLinkNotFound)
+			return resp, &LinkNotFoundError{
+				Rel:       navigateLinks[i],
+				Hop:       i,
+				URL:       base,
+				Available: links.Rels(),
+			}
+		}
+		vars, hasVars := o.templateVars[link.Rel]
+		templated := link.Templated || hasVars
+		if templated {
+			expanded, err := link.Expand(vars)
+			if err != nil {
+				return resp, err
+			}
+			url = ResolveHref(base, expanded)
+			// the expansion depends on vars, so neither this nor later hops can be cached
+			cacheable = false
+		} else {
+			url = ResolveHref(base, link.Href)
+		}
+		if c.LinkCache != nil && cacheable {
+			c.LinkCache.set(start, navigateLinks[:i+1], url)
+		}
+
+		var embedded json.RawMessage
+		if extractor, ok := c.dialect().(EmbeddedExtractor); ok && !o.forceFetch && !templated {
+			embedded, _ = extractor.ExtractEmbedded(doc, *link)
 		}
-		url = link.Href
+		doc = embedded
 	}
 
-	req, err := http.NewRequest(method, url, body)
+	target, err := addQuery(url, o.query)
+	if err != nil {
+		return nil, err
+	}
+	if o.trace != nil {
+		o.trace.URL = target
+	}
+	if o.dryRun != nil {
+		*o.dryRun = target
+		return nil, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
 	if err != nil {
 		return nil, err
 	}
 
-	for n, v := range c.DefaultHeaders {
+	for n, v := range c.defaultHeaders() {
 		req.Header.Set(n, v)
 	}
 	for n, v := range headers {
@@ -139,9 +354,15 @@ func (c *Client) Do(method string, url string, navigateLinks Navigate, headers H
 		}
 	}
 
-	resp, err := c.Http.Do(req)
-	if err != nil {
-		return resp, err
+	inHand := doc != nil && method == "GET" && body == nil && len(o.query) == 0
+	if inHand {
+		// the target was embedded in the last document, so no need to fetch it
+		resp = embeddedResponse(req, doc)
+	} else {
+		resp, err = c.Http.Do(req)
+		if err != nil {
+			return resp, err
+		}
 	}
 
 	respbody, err := ioutil.ReadAll(resp.Body)
@@ -150,8 +371,21 @@ func (c *Client) Do(method string, url string, navigateLinks Navigate, headers H
 	}
 	resp.Body.Close()
 
+	if o.trace != nil {
+		links, _ := c.dialect().ExtractLinks(respbody)
+		o.trace.hop(req.URL.String(), resp.StatusCode, inHand, links)
+	}
+
 	if resp.StatusCode >= http.StatusBadRequest {
-		return resp, errors.New(ErrorHttpStatus)
+		if c.LinkCache != nil && invalidatesLinks(resp.StatusCode) {
+			c.LinkCache.Invalidate(url)
+		}
+		return resp, &HTTPError{
+			StatusCode: resp.StatusCode,
+			Method:     method,
+			URL:        req.URL.String(),
+			Body:       respbody,
+		}
 	}
 
 	if result != nil {
@@ -159,31 +393,164 @@ func (c *Client) Do(method string, url string, navigateLinks Navigate, headers H
 		if err != nil {
 			return resp, err
 		}
+		// relative hrefs are relative to the document they came from
+		base := req.URL
+		if resp.Request != nil && resp.Request.URL != nil {
+			base = resp.Request.URL
+		}
+		resolveLinks(base, reflect.ValueOf(result))
 	}
 
 	return resp, nil
 }
 
 // Get is a small wrapper around Do
-func (c *Client) Get(url string, navigateLinks Navigate, headers Headers, body io.Reader, response interface{}) (*http.Response, error) {
-	return c.Do("GET", url, navigateLinks, headers, body, response)
+func (c *Client) Get(url string, navigateLinks Navigate, headers Headers, body io.Reader, response interface{}, opts ...RequestOption) (*http.Response, error) {
+	return c.GetContext(context.Background(), url, navigateLinks, headers, body, response, opts...)
+}
+
+// GetContext is a small wrapper around DoContext
+func (c *Client) GetContext(ctx context.Context, url string, navigateLinks Navigate, headers Headers, body io.Reader, response interface{}, opts ...RequestOption) (*http.Response, error) {
+	return c.DoContext(ctx, "GET", url, navigateLinks, headers, body, response, opts...)
 }
 
 // Post is a small wrapper around Do
-func (c *Client) Post(url string, navigateLinks Navigate, headers Headers, body io.Reader, response interface{}) (*http.Response, error) {
-	return c.Do("POST", url, navigateLinks, headers, body, response)
+func (c *Client) Post(url string, navigateLinks Navigate, headers Headers, body io.Reader, response interface{}, opts ...RequestOption) (*http.Response, error) {
+	return c.PostContext(context.Background(), url, navigateLinks, headers, body, response, opts...)
+}
+
+// PostContext is a small wrapper around DoContext
+func (c *Client) PostContext(ctx context.Context, url string, navigateLinks Navigate, headers Headers, body io.Reader, response interface{}, opts ...RequestOption) (*http.Response, error) {
+	return c.DoContext(ctx, "POST", url, navigateLinks, headers, body, response, opts...)
 }
 
 // PostForm is a small wrapper around Do
-func (c *Client) PostForm(url string, navigateLinks Navigate, headers Headers, data url.Values, response interface{}) (*http.Response, error) {
+func (c *Client) PostForm(url string, navigateLinks Navigate, headers Headers, data url.Values, response interface{}, opts ...RequestOption) (*http.Response, error) {
+	return c.PostFormContext(context.Background(), url, navigateLinks, headers, data, response, opts...)
+}
+
+// PostFormContext is a small wrapper around DoContext
+func (c *Client) PostFormContext(ctx context.Context, url string, navigateLinks Navigate, headers Headers, data url.Values, response interface{}, opts ...RequestOption) (*http.Response, error) {
 	if headers == nil {
 		headers = Headers{}
 	}
 	headers["Content-Type"] = "application/x-www-form-urlencoded"
-	return c.Do("POST", url, navigateLinks, headers, strings.NewReader(data.Encode()), response)
+	return c.DoContext(ctx, "POST", url, navigateLinks, headers, strings.NewReader(data.Encode()), response, opts...)
 }
 
 // Delete is a small wrapper around Do
-func (c *Client) Delete(url string, navigateLinks Navigate, headers Headers, body io.Reader, response interface{}) (*http.Response, error) {
-	return c.Do("DELETE", url, navigateLinks, headers, body, response)
+func (c *Client) Delete(url string, navigateLinks Navigate, headers Headers, body io.Reader, response interface{}, opts ...RequestOption) (*http.Response, error) {
+	return c.DeleteContext(context.Background(), url, navigateLinks, headers, body, response, opts...)
+}
+
+// DeleteContext is a small wrapper around DoContext
+func (c *Client) DeleteContext(ctx context.Context, url string, navigateLinks Navigate, headers Headers, body io.Reader, response interface{}, opts ...RequestOption) (*http.Response, error) {
+	return c.DoContext(ctx, "DELETE", url, navigateLinks, headers, body, response, opts...)
+}
+
+// Put is a small wrapper around Do
+func (c *Client) Put(url string, navigateLinks Navigate, headers Headers, body io.Reader, response interface{}, opts ...RequestOption) (*http.Response, error) {
+	return c.PutContext(context.Background(), url, navigateLinks, headers, body, response, opts...)
+}
+
+// PutContext is a small wrapper around DoContext
+func (c *Client) PutContext(ctx context.Context, url string, navigateLinks Navigate, headers Headers, body io.Reader, response interface{}, opts ...RequestOption) (*http.Response, error) {
+	return c.DoContext(ctx, "PUT", url, navigateLinks, headers, body, response, opts...)
+}
+
+// Patch is a small wrapper around Do
+func (c *Client) Patch(url string, navigateLinks Navigate, headers Headers, body io.Reader, response interface{}, opts ...RequestOption) (*http.Response, error) {
+	return c.PatchContext(context.Background(), url, navigateLinks, headers, body, response, opts...)
+}
+
+// PatchContext is a small wrapper around DoContext
+func (c *Client) PatchContext(ctx context.Context, url string, navigateLinks Navigate, headers Headers, body io.Reader, response interface{}, opts ...RequestOption) (*http.Response, error) {
+	return c.DoContext(ctx, "PATCH", url, navigateLinks, headers, body, response, opts...)
+}
+
+// Head is a small wrapper around Do
+func (c *Client) Head(url string, navigateLinks Navigate, headers Headers, opts ...RequestOption) (*http.Response, error) {
+	return c.HeadContext(context.Background(), url, navigateLinks, headers, opts...)
+}
+
+// HeadContext is a small wrapper around DoContext
+func (c *Client) HeadContext(ctx context.Context, url string, navigateLinks Navigate, headers Headers, opts ...RequestOption) (*http.Response, error) {
+	return c.DoContext(ctx, "HEAD", url, navigateLinks, headers, nil, nil, opts...)
+}
+
+// Options is a small wrapper around Do. See AllowedMethods for reading the result.
+func (c *Client) Options(url string, navigateLinks Navigate, headers Headers, opts ...RequestOption) (*http.Response, error) {
+	return c.OptionsContext(context.Background(), url, navigateLinks, headers, opts...)
+}
+
+// OptionsContext is a small wrapper around DoContext
+func (c *Client) OptionsContext(ctx context.Context, url string, navigateLinks Navigate, headers Headers, opts ...RequestOption) (*http.Response, error) {
+	return c.DoContext(ctx, "OPTIONS", url, navigateLinks, headers, nil, nil, opts...)
+}
+
+// AllowedMethods returns the methods listed in a response's Allow header, e.g. following Options
+func AllowedMethods(resp *http.Response) []string {
+	methods := []string{}
+	if resp == nil {
+		return methods
+	}
+	for _, allow := range resp.Header.Values("Allow") {
+		for _, method := range strings.Split(allow, ",") {
+			method = strings.TrimSpace(method)
+			if method != "" {
+				methods = append(methods, strings.ToUpper(method))
+			}
+		}
+	}
+	return methods
+}
+
+// DoJSON is the same as Do, except that `request` is marshalled to JSON for the body.
+// Content-Type defaults to "application/json" but can be overridden via `headers`
+// for vendor specific media types.
+func (c *Client) DoJSON(method string, url string, navigateLinks Navigate, headers Headers, request interface{}, result interface{}, opts ...RequestOption) (*http.Response, error) {
+	return c.DoJSONContext(context.Background(), method, url, navigateLinks, headers, request, result, opts...)
+}
+
+// DoJSONContext is the same as DoContext, except that `request` is marshalled to JSON for the body
+func (c *Client) DoJSONContext(ctx context.Context, method string, url string, navigateLinks Navigate, headers Headers, request interface{}, result interface{}, opts ...RequestOption) (*http.Response, error) {
+	buf, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	h := Headers{"Content-Type": "application/json"}
+	for n, v := range headers {
+		h[n] = v
+	}
+	return c.DoContext(ctx, method, url, navigateLinks, h, bytes.NewReader(buf), result, opts...)
+}
+
+// PostJSON is a small wrapper around DoJSON
+func (c *Client) PostJSON(url string, navigateLinks Navigate, headers Headers, request interface{}, response interface{}, opts ...RequestOption) (*http.Response, error) {
+	return c.DoJSONContext(context.Background(), "POST", url, navigateLinks, headers, request, response, opts...)
+}
+
+// PostJSONContext is a small wrapper around DoJSONContext
+func (c *Client) PostJSONContext(ctx context.Context, url string, navigateLinks Navigate, headers Headers, request interface{}, response interface{}, opts ...RequestOption) (*http.Response, error) {
+	return c.DoJSONContext(ctx, "POST", url, navigateLinks, headers, request, response, opts...)
+}
+
+// PutJSON is a small wrapper around DoJSON
+func (c *Client) PutJSON(url string, navigateLinks Navigate, headers Headers, request interface{}, response interface{}, opts ...RequestOption) (*http.Response, error) {
+	return c.DoJSONContext(context.Background(), "PUT", url, navigateLinks, headers, request, response, opts...)
+}
+
+// PutJSONContext is a small wrapper around DoJSONContext
+func (c *Client) PutJSONContext(ctx context.Context, url string, navigateLinks Navigate, headers Headers, request interface{}, response interface{}, opts ...RequestOption) (*http.Response, error) {
+	return c.DoJSONContext(ctx, "PUT", url, navigateLinks, headers, request, response, opts...)
+}
+
+// PatchJSON is a small wrapper around DoJSON
+func (c *Client) PatchJSON(url string, navigateLinks Navigate, headers Headers, request interface{}, response interface{}, opts ...RequestOption) (*http.Response, error) {
+	return c.DoJSONContext(context.Background(), "PATCH", url, navigateLinks, headers, request, response, opts...)
+}
+
+// PatchJSONContext is a small wrapper around DoJSONContext
+func (c *Client) PatchJSONContext(ctx context.Context, url string, navigateLinks Navigate, headers Headers, request interface{}, response interface{}, opts ...RequestOption) (*http.Response, error) {
+	return c.DoJSONContext(ctx, "PATCH", url, navigateLinks, headers, request, response, opts...)
 }
@@ -1,6 +1,7 @@
 package hateoas
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
@@ -140,7 +141,7 @@ func TestBob(t *testing.T) {
 	assert.Equal("over the rainbow", where.Where)
 
 	resp, err = client.Get("", Navigate{"bob", "nowhere"}, nil, nil, &where)
-	assert.Equal(ErrorLinkNotFound, err)
+	assert.True(IsLinkNotFound(err))
 }
 
 type httpLogger struct {
@@ -193,3 +194,121 @@ func TestSkipTLS(t *testing.T) {
 	assert.Nil(err)
 	assert.Equal("bob", bob.Name)
 }
+
+type cancellingDoer struct {
+	http.Client
+	cancel   context.CancelFunc
+	requests int
+}
+
+func (c *cancellingDoer) Do(req *http.Request) (*http.Response, error) {
+	c.requests++
+	resp, err := c.Client.Do(req)
+	c.cancel()
+	return resp, err
+}
+
+func TestContext(t *testing.T) {
+	assert := assert.New(t)
+
+	client := Create(&Client{
+		EntryURL: ts.URL,
+	})
+
+	var where Where
+	_, err := client.GetContext(context.Background(), "", Navigate{"bob", "where"}, nil, nil, &where)
+	assert.Nil(err)
+	assert.Equal("over the rainbow", where.Where)
+
+	// cancelled after the first hop, so the traversal should stop there
+	ctx, cancel := context.WithCancel(context.Background())
+	doer := &cancellingDoer{cancel: cancel}
+	client = Create(&Client{
+		EntryURL: ts.URL,
+		Http:     doer,
+	})
+	_, err = client.GetContext(ctx, "", Navigate{"bob", "where"}, nil, nil, &where)
+	assert.ErrorIs(err, context.Canceled)
+	assert.Equal(1, doer.requests)
+}
+
+func TestVerbs(t *testing.T) {
+	assert := assert.New(t)
+
+	var method, contentType string
+	var body Bob
+	vs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		contentType = r.Header.Get("Content-Type")
+		body = Bob{}
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Allow", "GET, PUT,patch")
+		if r.Method != "HEAD" && r.Method != "OPTIONS" {
+			json.NewEncoder(w).Encode(body)
+		}
+	}))
+	defer vs.Close()
+
+	client := Create(&Client{
+		EntryURL: vs.URL,
+	})
+
+	var result Bob
+	_, err := client.PutJSON("", nil, nil, Bob{Name: `bob "the builder"`}, &result)
+	assert.Nil(err)
+	assert.Equal("PUT", method)
+	assert.Equal("application/json", contentType)
+	assert.Equal(`bob "the builder"`, body.Name)
+	assert.Equal(`bob "the builder"`, result.Name)
+
+	_, err = client.PatchJSON("", nil, Headers{"Content-Type": "application/merge-patch+json"}, Bob{Name: "bob"}, &result)
+	assert.Nil(err)
+	assert.Equal("PATCH", method)
+	assert.Equal("application/merge-patch+json", contentType)
+
+	_, err = client.PostJSON("", nil, nil, Bob{Name: "bob"}, nil)
+	assert.Nil(err)
+	assert.Equal("POST", method)
+
+	_, err = client.Head("", nil, nil)
+	assert.Nil(err)
+	assert.Equal("HEAD", method)
+
+	resp, err := client.Options("", nil, nil)
+	assert.Nil(err)
+	assert.Equal("OPTIONS", method)
+	assert.Equal([]string{"GET", "PUT", "PATCH"}, AllowedMethods(resp))
+
+	_, err = client.PostJSON("", nil, nil, make(chan int), nil)
+	assert.NotNil(err)
+}
+
+func TestConcurrentHeaders(t *testing.T) {
+	assert := assert.New(t)
+
+	client := Create(&Client{
+		EntryURL: ts.URL,
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			client.SetDefaultHeader("Authorization", fmt.Sprintf("Bearer %d", i))
+			client.DelDefaultHeader("Authorization")
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		var bob Bob
+		_, err := client.Get("", Navigate{"bob"}, nil, nil, &bob)
+		assert.Nil(err)
+	}
+	<-done
+
+	client.SetDefaultHeader("X-Who", "original")
+	clone := client.Clone()
+	clone.SetDefaultHeader("X-Who", "clone")
+	assert.Equal("original", client.DefaultHeaders["X-Who"])
+	assert.Equal("clone", clone.DefaultHeaders["X-Who"])
+	assert.Equal(client.Http, clone.Http)
+}
@@ -0,0 +1,360 @@
+package hateoas
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CachedResponse is what a CacheStore holds for each cached GET
+type CachedResponse struct {
+	StatusCode int         `json:"StatusCode"`
+	Header     http.Header `json:"Header"`
+	Body       []byte      `json:"Body"`
+	Stored     time.Time   `json:"Stored"`
+}
+
+// CacheStore is a storage backend for CachingDoer. Keys are already hashed,
+// so are safe to use as file names etc.
+type CacheStore interface {
+	Get(key string) (*CachedResponse, bool)
+	Set(key string, resp *CachedResponse)
+	Delete(key string)
+}
+
+// CachingDoer is a HTTPDoer which caches GET responses, honouring Cache-Control
+// max-age/no-cache/no-store and revalidating stale entries with
+// If-None-Match/If-Modified-Since. Any successful non-GET request drops the
+// cached entry for its URL.
+type CachingDoer struct {
+	Http  HTTPDoer
+	Store CacheStore
+}
+
+// CreateCachingDoer wraps `doer` (defaulting to a plain *http.Client) with a cache
+// backed by `store` (defaulting to a 1000 entry in-memory LRU)
+func CreateCachingDoer(doer HTTPDoer, store CacheStore) *CachingDoer {
+	if doer == nil {
+		doer = &http.Client{}
+	}
+	if store == nil {
+		store = CreateMemoryCacheStore(1000)
+	}
+	return &CachingDoer{
+		Http:  doer,
+		Store: store,
+	}
+}
+
+// cacheKey includes the headers most likely to change the representation,
+// in particular Authorization as different credentials see different links
+func cacheKey(req *http.Request) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s", req.URL.String(), req.Header.Get("Accept"), req.Header.Get("Authorization"))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// keyedVary reports whether every header named by the response's Vary is
+// already part of cacheKey; responses varying on anything else are not stored
+func keyedVary(header http.Header) bool {
+	for _, vary := range header.Values("Vary") {
+		for _, name := range strings.Split(vary, ",") {
+			switch http.CanonicalHeaderKey(strings.TrimSpace(name)) {
+			case "", "Accept", "Authorization":
+			default:
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// cacheControl parses a Cache-Control header into directive => value
+func cacheControl(header http.Header) map[string]string {
+	cc := map[string]string{}
+	for _, part := range strings.Split(header.Get("Cache-Control"), ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		name := strings.ToLower(strings.TrimSpace(kv[0]))
+		if len(kv) == 2 {
+			cc[name] = strings.Trim(strings.TrimSpace(kv[1]), `"`)
+		} else {
+			cc[name] = ""
+		}
+	}
+	return cc
+}
+
+// fresh reports whether a cached response can be used without revalidation
+func (cr *CachedResponse) fresh(now time.Time) bool {
+	cc := cacheControl(cr.Header)
+	if _, noCache := cc["no-cache"]; noCache {
+		return false
+	}
+	maxAge, exists := cc["max-age"]
+	if !exists {
+		return false
+	}
+	seconds, err := strconv.Atoi(maxAge)
+	if err != nil {
+		return false
+	}
+	return now.Before(cr.Stored.Add(time.Duration(seconds) * time.Second))
+}
+
+func (cr *CachedResponse) response(req *http.Request) *http.Response {
+	header := cr.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", cr.StatusCode, http.StatusText(cr.StatusCode)),
+		StatusCode:    cr.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(cr.Body)),
+		ContentLength: int64(len(cr.Body)),
+		Request:       req,
+	}
+}
+
+// Do implements HTTPDoer
+func (c *CachingDoer) Do(req *http.Request) (*http.Response, error) {
+	key := cacheKey(req)
+
+	if req.Method != "GET" {
+		resp, err := c.Http.Do(req)
+		if err == nil && resp.StatusCode < http.StatusBadRequest && req.Method != "HEAD" && req.Method != "OPTIONS" {
+			c.Store.Delete(key)
+		}
+		return resp, err
+	}
+
+	if _, noStore := cacheControl(req.Header)["no-store"]; noStore {
+		return c.Http.Do(req)
+	}
+
+	cached, exists := c.Store.Get(key)
+	if exists && cached.fresh(time.Now()) {
+		return cached.response(req), nil
+	}
+
+	outgoing := req
+	if exists {
+		etag := cached.Header.Get("ETag")
+		lastModified := cached.Header.Get("Last-Modified")
+		if etag != "" || lastModified != "" {
+			outgoing = req.Clone(req.Context())
+			if etag != "" && outgoing.Header.Get("If-None-Match") == "" {
+				outgoing.Header.Set("If-None-Match", etag)
+			}
+			if lastModified != "" && outgoing.Header.Get("If-Modified-Since") == "" {
+				outgoing.Header.Set("If-Modified-Since", lastModified)
+			}
+		}
+	}
+
+	resp, err := c.Http.Do(outgoing)
+	if err != nil {
+		return resp, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && exists && outgoing != req {
+		resp.Body.Close()
+		// a 304 may carry updated caching headers
+		header := cached.Header.Clone()
+		for n, v := range resp.Header {
+			header[n] = v
+		}
+		revalidated := &CachedResponse{
+			StatusCode: cached.StatusCode,
+			Header:     header,
+			Body:       cached.Body,
+			Stored:     time.Now(),
+		}
+		c.Store.Set(key, revalidated)
+		return revalidated.response(req), nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+
+	cc := cacheControl(resp.Header)
+	if _, noStore := cc["no-store"]; noStore {
+		c.Store.Delete(key)
+		return resp, nil
+	}
+	if !keyedVary(resp.Header) {
+		c.Store.Delete(key)
+		return resp, nil
+	}
+	_, hasMaxAge := cc["max-age"]
+	if !hasMaxAge && resp.Header.Get("ETag") == "" && resp.Header.Get("Last-Modified") == "" {
+		return resp, nil
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return resp, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	c.Store.Set(key, &CachedResponse{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+		Body:       body,
+		Stored:     time.Now(),
+	})
+	return resp, nil
+}
+
+// MemoryCacheStore is an in-memory LRU CacheStore, safe for concurrent use
+type MemoryCacheStore struct {
+	Capacity int
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type memoryCacheEntry struct {
+	key  string
+	resp *CachedResponse
+}
+
+// CreateMemoryCacheStore returns a MemoryCacheStore holding at most `capacity` responses
+func CreateMemoryCacheStore(capacity int) *MemoryCacheStore {
+	return &MemoryCacheStore{
+		Capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// Get implements CacheStore
+func (m *MemoryCacheStore) Get(key string) (*CachedResponse, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	elem, exists := m.entries[key]
+	if !exists {
+		return nil, false
+	}
+	m.order.MoveToFront(elem)
+	return elem.Value.(*memoryCacheEntry).resp, true
+}
+
+// Set implements CacheStore
+func (m *MemoryCacheStore) Set(key string, resp *CachedResponse) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if elem, exists := m.entries[key]; exists {
+		elem.Value.(*memoryCacheEntry).resp = resp
+		m.order.MoveToFront(elem)
+		return
+	}
+	m.entries[key] = m.order.PushFront(&memoryCacheEntry{key: key, resp: resp})
+	for m.Capacity > 0 && m.order.Len() > m.Capacity {
+		oldest := m.order.Back()
+		m.order.Remove(oldest)
+		delete(m.entries, oldest.Value.(*memoryCacheEntry).key)
+	}
+}
+
+// Delete implements CacheStore
+func (m *MemoryCacheStore) Delete(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if elem, exists := m.entries[key]; exists {
+		m.order.Remove(elem)
+		delete(m.entries, key)
+	}
+}
+
+// Len returns the number of cached responses
+func (m *MemoryCacheStore) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.order.Len()
+}
+
+// DiskCacheStore is a CacheStore keeping one JSON file per response in Dir.
+// Responses may contain sensitive data so files are created 0600.
+type DiskCacheStore struct {
+	Dir string
+}
+
+// CreateDiskCacheStore returns a DiskCacheStore, creating `dir` if required
+func CreateDiskCacheStore(dir string) (*DiskCacheStore, error) {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+	return &DiskCacheStore{Dir: dir}, nil
+}
+
+func (d *DiskCacheStore) path(key string) string {
+	return filepath.Join(d.Dir, key+".json")
+}
+
+// Get implements CacheStore
+func (d *DiskCacheStore) Get(key string) (*CachedResponse, bool) {
+	buf, err := ioutil.ReadFile(d.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var resp CachedResponse
+	err = json.Unmarshal(buf, &resp)
+	if err != nil {
+		return nil, false
+	}
+	return &resp, true
+}
+
+// Set implements CacheStore. Write errors are ignored, the response simply isn't cached.
+func (d *DiskCacheStore) Set(key string, resp *CachedResponse) {
+	buf, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+	// write then rename, so that concurrent readers never see a partial file
+	tmp, err := ioutil.TempFile(d.Dir, key+".tmp")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(buf)
+	tmp.Close()
+	if err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	if os.Rename(tmp.Name(), d.path(key)) != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+// Delete implements CacheStore
+func (d *DiskCacheStore) Delete(key string) {
+	os.Remove(d.path(key))
+}
@@ -0,0 +1,178 @@
+package hateoas
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type cacheTestServer struct {
+	*httptest.Server
+	hits         int
+	notModified  int
+	cacheControl string
+}
+
+func createCacheTestServer() *cacheTestServer {
+	cs := &cacheTestServer{}
+	cs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cs.hits++
+		if cs.cacheControl != "" {
+			w.Header().Set("Cache-Control", cs.cacheControl)
+		}
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			cs.notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fmt.Fprint(w, `{"Where":"over the rainbow"}`)
+	}))
+	return cs
+}
+
+func testCachingDoer(t *testing.T, store CacheStore) {
+	assert := assert.New(t)
+
+	cs := createCacheTestServer()
+	defer cs.Close()
+
+	client := Create(&Client{
+		EntryURL: cs.URL,
+		Http:     CreateCachingDoer(nil, store),
+	})
+
+	var where Where
+	_, err := client.Get("", nil, nil, nil, &where)
+	assert.Nil(err)
+	assert.Equal("over the rainbow", where.Where)
+	assert.Equal(1, cs.hits)
+	assert.Equal(0, cs.notModified)
+
+	// revalidated with If-None-Match
+	where = Where{}
+	resp, err := client.Get("", nil, nil, nil, &where)
+	assert.Nil(err)
+	assert.Equal(http.StatusOK, resp.StatusCode)
+	assert.Equal("over the rainbow", where.Where)
+	assert.Equal(2, cs.hits)
+	assert.Equal(1, cs.notModified)
+
+	// revalidation picks up the new max-age, after which it is fresh so no request at all
+	cs.cacheControl = "max-age=60"
+	_, err = client.Get("", nil, nil, nil, &where)
+	assert.Nil(err)
+	assert.Equal(3, cs.hits)
+	assert.Equal(2, cs.notModified)
+	_, err = client.Get("", nil, nil, nil, &where)
+	assert.Nil(err)
+	assert.Equal(3, cs.hits)
+
+	// no-store on the request bypasses the cache
+	_, err = client.Get("", nil, Headers{"Cache-Control": "no-store"}, nil, &where)
+	assert.Nil(err)
+	assert.Equal(4, cs.hits)
+
+	// unsafe methods invalidate
+	_, err = client.Delete("", nil, nil, nil, nil)
+	assert.Nil(err)
+	assert.Equal(5, cs.hits)
+	_, err = client.Get("", nil, nil, nil, &where)
+	assert.Nil(err)
+	assert.Equal(6, cs.hits)
+	assert.Equal(2, cs.notModified)
+
+	// no-store on the response is never cached
+	_, err = client.Delete("", nil, nil, nil, nil)
+	assert.Nil(err)
+	cs.cacheControl = "no-store"
+	_, err = client.Get("", nil, nil, nil, &where)
+	assert.Nil(err)
+	_, err = client.Get("", nil, nil, nil, &where)
+	assert.Nil(err)
+	assert.Equal(9, cs.hits)
+	assert.Equal(2, cs.notModified)
+}
+
+func TestCachingDoerMemory(t *testing.T) {
+	testCachingDoer(t, CreateMemoryCacheStore(10))
+}
+
+func TestCachingDoerDisk(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hateoas-cache")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	store, err := CreateDiskCacheStore(dir)
+	assert.Nil(t, err)
+	testCachingDoer(t, store)
+}
+
+func TestMemoryCacheStoreLRU(t *testing.T) {
+	assert := assert.New(t)
+
+	store := CreateMemoryCacheStore(2)
+	store.Set("a", &CachedResponse{StatusCode: 200})
+	store.Set("b", &CachedResponse{StatusCode: 200})
+	_, exists := store.Get("a")
+	assert.True(exists)
+
+	store.Set("c", &CachedResponse{StatusCode: 200})
+	assert.Equal(2, store.Len())
+	_, exists = store.Get("b")
+	assert.False(exists)
+	_, exists = store.Get("a")
+	assert.True(exists)
+	_, exists = store.Get("c")
+	assert.True(exists)
+}
+
+func TestCachingDoerHeaders(t *testing.T) {
+	assert := assert.New(t)
+
+	vary := ""
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("Cache-Control", "max-age=60")
+		if vary != "" {
+			w.Header().Set("Vary", vary)
+		}
+		fmt.Fprintf(w, `{"Where":"%s"}`, r.Header.Get("Accept-Language"))
+	}))
+	defer server.Close()
+
+	store := CreateMemoryCacheStore(10)
+	doer := CreateCachingDoer(nil, store)
+
+	get := func(language string) *http.Response {
+		req, _ := http.NewRequest("GET", server.URL, nil)
+		req.Header.Set("Accept-Language", language)
+		resp, err := doer.Do(req)
+		assert.Nil(err)
+		return resp
+	}
+
+	// changing the returned headers must not change the cached entry
+	vary = "accept"
+	resp := get("en")
+	resp.Header.Set("Cache-Control", "no-cache")
+	resp = get("en")
+	assert.Equal(1, hits)
+	assert.Equal("max-age=60", resp.Header.Get("Cache-Control"))
+
+	// a response varying on a header outside the key is not stored
+	store = CreateMemoryCacheStore(10)
+	doer = CreateCachingDoer(nil, store)
+	vary = "Accept, Accept-Language"
+	get("en")
+	body, _ := ioutil.ReadAll(get("fr").Body)
+	assert.Equal(`{"Where":"fr"}`, string(body))
+	assert.Equal(3, hits)
+	assert.Equal(0, store.Len())
+}
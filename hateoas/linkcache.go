@@ -0,0 +1,111 @@
+package hateoas
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LinkCache remembers the href that a Navigate list resolved to, so that
+// repeated calls don't have to GET every intermediate resource again.
+// Entries are keyed by the start URL and the chain of rels followed.
+//
+// Note that the cache does not know about credentials, so a Client whose
+// Authorization header changes may want to Purge it.
+// A LinkCache is safe for concurrent use and may be shared between Clients.
+type LinkCache struct {
+	// TTL is how long a resolved href is trusted for. Zero means forever.
+	TTL time.Duration
+
+	mu      sync.Mutex
+	entries map[string]linkCacheEntry
+}
+
+type linkCacheEntry struct {
+	href    string
+	expires time.Time
+}
+
+// CreateLinkCache returns an empty LinkCache whose entries live for `ttl`
+func CreateLinkCache(ttl time.Duration) *LinkCache {
+	return &LinkCache{
+		TTL:     ttl,
+		entries: make(map[string]linkCacheEntry),
+	}
+}
+
+func linkCacheKey(start string, rels Navigate) string {
+	return start + "\x00" + strings.Join(rels, "\x00")
+}
+
+// lookup finds the longest prefix of `rels` that has a resolved href cached,
+// returning how many rels were consumed along with the href
+func (lc *LinkCache) lookup(start string, rels Navigate) (int, string) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	now := time.Now()
+	for n := len(rels); n > 0; n-- {
+		key := linkCacheKey(start, rels[:n])
+		entry, exists := lc.entries[key]
+		if !exists {
+			continue
+		}
+		if !entry.expires.IsZero() && now.After(entry.expires) {
+			delete(lc.entries, key)
+			continue
+		}
+		return n, entry.href
+	}
+	return 0, ""
+}
+
+func (lc *LinkCache) set(start string, rels Navigate, href string) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	if lc.entries == nil {
+		lc.entries = make(map[string]linkCacheEntry)
+	}
+	entry := linkCacheEntry{href: href}
+	if lc.TTL > 0 {
+		entry.expires = time.Now().Add(lc.TTL)
+	}
+	lc.entries[linkCacheKey(start, rels)] = entry
+}
+
+// Invalidate drops every entry that resolved to `href`
+func (lc *LinkCache) Invalidate(href string) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	for key, entry := range lc.entries {
+		if entry.href == href {
+			delete(lc.entries, key)
+		}
+	}
+}
+
+// Purge drops every entry
+func (lc *LinkCache) Purge() {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	lc.entries = make(map[string]linkCacheEntry)
+}
+
+// Len returns the number of entries currently held, including any that have expired
+// but not yet been looked up
+func (lc *LinkCache) Len() int {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	return len(lc.entries)
+}
+
+// invalidatesLinks reports whether a response status means that a cached href
+// pointing at it should no longer be trusted
+func invalidatesLinks(statusCode int) bool {
+	return statusCode == http.StatusNotFound || statusCode == http.StatusGone
+}
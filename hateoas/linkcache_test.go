@@ -0,0 +1,97 @@
+package hateoas
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLinkCache(t *testing.T) {
+	assert := assert.New(t)
+
+	logger := &httpLogger{Client: http.Client{}}
+	client := Create(&Client{
+		EntryURL:  ts.URL,
+		Http:      logger,
+		LinkCache: CreateLinkCache(time.Minute),
+	})
+
+	var where Where
+	_, err := client.Get("", Navigate{"bob", "where"}, nil, nil, &where)
+	assert.Nil(err)
+	assert.Equal("over the rainbow", where.Where)
+	assert.Equal(3, len(logger.logged))
+	assert.Equal(2, client.LinkCache.Len())
+
+	// fully cached, so straight to the end
+	_, err = client.Get("", Navigate{"bob", "where"}, nil, nil, &where)
+	assert.Nil(err)
+	assert.Equal("over the rainbow", where.Where)
+	assert.Equal(4, len(logger.logged))
+
+	// partially cached
+	var bob Bob
+	_, err = client.Get("", Navigate{"bob"}, nil, nil, &bob)
+	assert.Nil(err)
+	assert.Equal("bob", bob.Name)
+	assert.Equal(5, len(logger.logged))
+
+	client.LinkCache.Purge()
+	assert.Equal(0, client.LinkCache.Len())
+	_, err = client.Get("", Navigate{"bob", "where"}, nil, nil, &where)
+	assert.Nil(err)
+	assert.Equal(8, len(logger.logged))
+}
+
+func TestLinkCacheExpiry(t *testing.T) {
+	assert := assert.New(t)
+
+	lc := CreateLinkCache(time.Millisecond)
+	lc.set("http://localhost/", Navigate{"bob"}, "http://localhost/bob")
+	n, href := lc.lookup("http://localhost/", Navigate{"bob", "where"})
+	assert.Equal(1, n)
+	assert.Equal("http://localhost/bob", href)
+
+	time.Sleep(5 * time.Millisecond)
+	n, _ = lc.lookup("http://localhost/", Navigate{"bob", "where"})
+	assert.Equal(0, n)
+	assert.Equal(0, lc.Len())
+}
+
+func TestLinkCacheInvalidate(t *testing.T) {
+	assert := assert.New(t)
+
+	gone := false
+	var gs *httptest.Server
+	gs = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte(`{"Links":[{"rel":"bob","href":"` + gs.URL + `/bob"}]}`))
+		case "/bob":
+			if gone {
+				w.WriteHeader(http.StatusGone)
+				return
+			}
+			w.Write([]byte(`{"Name":"bob"}`))
+		}
+	}))
+	defer gs.Close()
+
+	client := Create(&Client{
+		EntryURL:  gs.URL,
+		LinkCache: CreateLinkCache(0),
+	})
+
+	var bob Bob
+	_, err := client.Get("", Navigate{"bob"}, nil, nil, &bob)
+	assert.Nil(err)
+	assert.Equal(1, client.LinkCache.Len())
+
+	gone = true
+	_, err = client.Get("", Navigate{"bob"}, nil, nil, &bob)
+	assert.True(IsGone(err))
+	assert.Equal(0, client.LinkCache.Len())
+}
@@ -0,0 +1,217 @@
+package hateoas
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Middleware wraps a HTTPDoer with some extra behaviour, see Chain
+type Middleware func(HTTPDoer) HTTPDoer
+
+// DoerFunc allows an ordinary function to be used as a HTTPDoer
+type DoerFunc func(*http.Request) (*http.Response, error)
+
+// Do implements HTTPDoer
+func (f DoerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Chain wraps `doer` (defaulting to a plain *http.Client) in each middleware.
+// The first middleware is the outermost, so sees each request first, e.g.
+//
+//	Http: Chain(nil, RequestID("", nil), RetryMiddleware(nil), Logging(logger))
+//
+// logs every attempt, retries included, with the same request ID.
+func Chain(doer HTTPDoer, middleware ...Middleware) HTTPDoer {
+	if doer == nil {
+		doer = &http.Client{}
+	}
+	for i := len(middleware) - 1; i >= 0; i-- {
+		doer = middleware[i](doer)
+	}
+	return doer
+}
+
+// CacheMiddleware wraps with a CachingDoer, see CreateCachingDoer
+func CacheMiddleware(store CacheStore) Middleware {
+	return func(next HTTPDoer) HTTPDoer {
+		return CreateCachingDoer(next, store)
+	}
+}
+
+// RetryMiddleware wraps with a RetryDoer, see CreateRetryDoer. `configure` is optional
+// and may adjust the defaults.
+func RetryMiddleware(configure func(*RetryDoer)) Middleware {
+	return func(next HTTPDoer) HTTPDoer {
+		r := CreateRetryDoer(next)
+		if configure != nil {
+			configure(r)
+		}
+		return r
+	}
+}
+
+// RateLimitMiddleware wraps with a RateLimitDoer, see CreateRateLimitDoer. `configure`
+// is optional and may set per-class limits etc.
+func RateLimitMiddleware(limit RateLimit, configure func(*RateLimitDoer)) Middleware {
+	return func(next HTTPDoer) HTTPDoer {
+		r := CreateRateLimitDoer(next, limit)
+		if configure != nil {
+			configure(r)
+		}
+		return r
+	}
+}
+
+// CircuitBreakerMiddleware wraps with a CircuitBreaker, see CreateCircuitBreaker.
+// `configure` is optional and may adjust the thresholds or set OnStateChange.
+func CircuitBreakerMiddleware(configure func(*CircuitBreaker)) Middleware {
+	return func(next HTTPDoer) HTTPDoer {
+		b := CreateCircuitBreaker(next)
+		if configure != nil {
+			configure(b)
+		}
+		return b
+	}
+}
+
+// DefaultRedactedHeaders are the headers whose values RedactHeaders hides by default
+var DefaultRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// RedactHeaders returns a copy of `header` with the values of DefaultRedactedHeaders and
+// any further `names` replaced, so that it is safe to log. Names are case insensitive.
+func RedactHeaders(header http.Header, names ...string) http.Header {
+	redacted := header.Clone()
+	if redacted == nil {
+		return http.Header{}
+	}
+	hidden := map[string]bool{}
+	for _, name := range append(append([]string{}, DefaultRedactedHeaders...), names...) {
+		hidden[http.CanonicalHeaderKey(name)] = true
+	}
+	for name, values := range redacted {
+		if hidden[http.CanonicalHeaderKey(name)] {
+			for i := range values {
+				values[i] = "REDACTED"
+			}
+		}
+	}
+	return redacted
+}
+
+func headerAttrs(header http.Header) []any {
+	attrs := []any{}
+	for name, values := range header {
+		attrs = append(attrs, slog.String(name, strings.Join(values, ", ")))
+	}
+	return attrs
+}
+
+// Logging logs each request with `logger` (defaulting to slog.Default()): a summary at Info
+// level, errors at Warn, and the request/response headers at Debug. Values of
+// DefaultRedactedHeaders and of any headers named in `redact` are never logged.
+func Logging(logger *slog.Logger, redact ...string) Middleware {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return func(next HTTPDoer) HTTPDoer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			ctx := req.Context()
+			attrs := []any{
+				slog.String("method", req.Method),
+				slog.String("url", req.URL.String()),
+			}
+			if id := RequestIDFromContext(ctx); id != "" {
+				attrs = append(attrs, slog.String("request_id", id))
+			} else if id := req.Header.Get(DefaultRequestIDHeader); id != "" {
+				attrs = append(attrs, slog.String("request_id", id))
+			}
+			if logger.Enabled(ctx, slog.LevelDebug) {
+				logger.DebugContext(ctx, "http request", append(attrs, slog.Group("headers", headerAttrs(RedactHeaders(req.Header, redact...))...))...)
+			}
+
+			start := time.Now()
+			resp, err := next.Do(req)
+			attrs = append(attrs, slog.Duration("duration", time.Since(start)))
+
+			if err != nil {
+				logger.WarnContext(ctx, "http request failed", append(attrs, slog.String("error", err.Error()))...)
+				return resp, err
+			}
+			attrs = append(attrs, slog.Int("status", resp.StatusCode))
+			logger.InfoContext(ctx, "http request", attrs...)
+			if logger.Enabled(ctx, slog.LevelDebug) {
+				logger.DebugContext(ctx, "http response", append(attrs, slog.Group("headers", headerAttrs(RedactHeaders(resp.Header, redact...))...))...)
+			}
+			return resp, err
+		})
+	}
+}
+
+// DefaultRequestIDHeader is the header RequestID sets when none is specified
+const DefaultRequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// ContextWithRequestID attaches a request ID to `ctx`, which the RequestID middleware will
+// then use for every request made with it (e.g. all the hops of a navigation)
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID attached by ContextWithRequestID, if any
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func randomRequestID() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// RequestID sets `header` (defaulting to DefaultRequestIDHeader) on every request which
+// doesn't already have it. The ID is taken from the request's context if present (see
+// ContextWithRequestID), or else made by `generate` (defaulting to 128 random bits).
+func RequestID(header string, generate func() string) Middleware {
+	if header == "" {
+		header = DefaultRequestIDHeader
+	}
+	if generate == nil {
+		generate = randomRequestID
+	}
+	return func(next HTTPDoer) HTTPDoer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get(header) != "" {
+				return next.Do(req)
+			}
+			id := RequestIDFromContext(req.Context())
+			if id == "" {
+				id = generate()
+			}
+			// requests shouldn't be modified by a HTTPDoer, so work on a copy
+			req = req.Clone(req.Context())
+			req.Header.Set(header, id)
+			return next.Do(req)
+		})
+	}
+}
+
+// Timing calls `observe` after every request with its outcome and how long it took,
+// e.g. to feed metrics
+func Timing(observe func(req *http.Request, resp *http.Response, err error, elapsed time.Duration)) Middleware {
+	return func(next HTTPDoer) HTTPDoer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next.Do(req)
+			observe(req, resp, err, time.Since(start))
+			return resp, err
+		})
+	}
+}
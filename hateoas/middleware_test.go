@@ -0,0 +1,132 @@
+package hateoas
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChain(t *testing.T) {
+	assert := assert.New(t)
+
+	order := []string{}
+	mark := func(name string) Middleware {
+		return func(next HTTPDoer) HTTPDoer {
+			return DoerFunc(func(req *http.Request) (*http.Response, error) {
+				order = append(order, name)
+				return next.Do(req)
+			})
+		}
+	}
+
+	client := Create(&Client{
+		EntryURL: ts.URL,
+		Http:     Chain(nil, mark("outer"), mark("inner")),
+	})
+	var bob Bob
+	_, err := client.Get("", Navigate{"bob"}, nil, nil, &bob)
+	assert.Nil(err)
+	assert.Equal("bob", bob.Name)
+	assert.Equal([]string{"outer", "inner", "outer", "inner"}, order)
+}
+
+func TestLogging(t *testing.T) {
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	client := Create(&Client{
+		EntryURL: ts.URL,
+		Http:     Chain(nil, RequestID("", func() string { return "abc123" }), Logging(logger, "X-Api-Key")),
+	})
+	client.SetDefaultHeader("Authorization", "Bearer sekrit")
+	client.SetDefaultHeader("X-Api-Key", "sekrit")
+
+	var bob Bob
+	_, err := client.Get("", Navigate{"bob"}, nil, nil, &bob)
+	assert.Nil(err)
+
+	out := buf.String()
+	assert.Contains(out, "method=GET")
+	assert.Contains(out, "status=200")
+	assert.Contains(out, "request_id=abc123")
+	assert.Contains(out, "headers.Authorization=REDACTED")
+	assert.Contains(out, "headers.X-Api-Key=REDACTED")
+	assert.NotContains(out, "sekrit")
+}
+
+func TestRequestID(t *testing.T) {
+	assert := assert.New(t)
+
+	ids := []string{}
+	client := Create(&Client{
+		EntryURL: ts.URL,
+		Http: Chain(nil, RequestID("X-Trace", nil), func(next HTTPDoer) HTTPDoer {
+			return DoerFunc(func(req *http.Request) (*http.Response, error) {
+				ids = append(ids, req.Header.Get("X-Trace"))
+				return next.Do(req)
+			})
+		}),
+	})
+
+	ctx := ContextWithRequestID(context.Background(), "from-context")
+	_, err := client.GetContext(ctx, "", Navigate{"bob"}, nil, nil, nil)
+	assert.Nil(err)
+	assert.Equal([]string{"from-context", "from-context"}, ids)
+
+	_, err = client.Get("", nil, Headers{"X-Trace": "explicit"}, nil, nil)
+	assert.Nil(err)
+	assert.Equal("explicit", ids[2])
+
+	_, err = client.Get("", nil, nil, nil, nil)
+	assert.Nil(err)
+	assert.Equal(32, len(ids[3]))
+}
+
+func TestTiming(t *testing.T) {
+	assert := assert.New(t)
+
+	statuses := []int{}
+	client := Create(&Client{
+		EntryURL: ts.URL,
+		Http: Chain(nil, Timing(func(req *http.Request, resp *http.Response, err error, elapsed time.Duration) {
+			assert.Nil(err)
+			assert.True(elapsed > 0)
+			statuses = append(statuses, resp.StatusCode)
+		})),
+	})
+
+	_, err := client.Get("", Navigate{"bob", "where"}, nil, nil, nil)
+	assert.Nil(err)
+	assert.Equal([]int{200, 200, 200}, statuses)
+}
+
+func TestRedactHeaders(t *testing.T) {
+	assert := assert.New(t)
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer sekrit")
+	header.Set("X-Api-Key", "sekrit")
+	header.Set("Accept", "application/json")
+
+	redacted := RedactHeaders(header)
+	assert.Equal("REDACTED", redacted.Get("Authorization"))
+	assert.Equal("sekrit", redacted.Get("X-Api-Key"))
+	assert.Equal("Bearer sekrit", header.Get("Authorization"))
+
+	// custom names add to the defaults, whatever their case
+	redacted = RedactHeaders(header, "x-api-key")
+	assert.Equal("REDACTED", redacted.Get("X-Api-Key"))
+	assert.Equal("REDACTED", redacted.Get("Authorization"))
+	assert.Equal("application/json", redacted.Get("Accept"))
+
+	// as do headers set without canonicalising
+	header = http.Header{"authorization": {"Bearer sekrit"}}
+	assert.Equal([]string{"REDACTED"}, RedactHeaders(header)["authorization"])
+}
@@ -0,0 +1,86 @@
+package hateoas
+
+import (
+	"net/url"
+)
+
+// RequestOption tweaks the behaviour of a single Client.Do call (or any of its wrappers)
+type RequestOption func(*requestOptions)
+
+type requestOptions struct {
+	templateVars map[string]TemplateVars
+	forceFetch   bool
+	query        url.Values
+	trace        *Trace
+	dryRun       *string
+}
+
+func collectOptions(opts []RequestOption) *requestOptions {
+	o := &requestOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithTemplateVars supplies the variables used to expand the link `rel` when it is
+// followed during navigation, e.g. jumping straight to an item with "{id}". Templated
+// links are always expanded, so any without variables just lose their optional parts.
+func WithTemplateVars(rel string, vars TemplateVars) RequestOption {
+	return func(o *requestOptions) {
+		if o.templateVars == nil {
+			o.templateVars = map[string]TemplateVars{}
+		}
+		o.templateVars[rel] = vars
+	}
+}
+
+// WithForceFetch makes navigation GET every resource, even where its representation
+// is already embedded in the parent document (see EmbeddedExtractor)
+func WithForceFetch() RequestOption {
+	return func(o *requestOptions) {
+		o.forceFetch = true
+	}
+}
+
+// WithQuery adds query parameters to the final request (but not to any of the
+// intermediate GETs made whilst navigating). Values are added to, rather than
+// replacing, any the URL already has.
+func WithQuery(query url.Values) RequestOption {
+	return func(o *requestOptions) {
+		if o.query == nil {
+			o.query = url.Values{}
+		}
+		for n, v := range query {
+			o.query[n] = append(o.query[n], v...)
+		}
+	}
+}
+
+// withoutURLOptions keeps those of `opts` which don't change the URL requested,
+// for following links such as "next" which are already complete
+func withoutURLOptions(opts []RequestOption) []RequestOption {
+	kept := collectOptions(opts)
+	kept.templateVars = nil
+	kept.query = nil
+	return []RequestOption{func(o *requestOptions) {
+		*o = *kept
+	}}
+}
+
+// addQuery merges `query` into the URL
+func addQuery(rawurl string, query url.Values) (string, error) {
+	if len(query) == 0 {
+		return rawurl, nil
+	}
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	for n, v := range query {
+		q[n] = append(q[n], v...)
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
@@ -0,0 +1,29 @@
+package hateoas
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithQuery(t *testing.T) {
+	assert := assert.New(t)
+
+	queries := []string{}
+	qs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		w.Write([]byte(`{"Links":[{"rel":"things","href":"/things?sort=name"}]}`))
+	}))
+	defer qs.Close()
+
+	client := Create(&Client{
+		EntryURL: qs.URL,
+	})
+
+	_, err := client.Get("", Navigate{"things"}, nil, nil, nil, WithQuery(url.Values{"pageSize": {"10"}}), WithQuery(url.Values{"startIndex": {"20"}}))
+	assert.Nil(err)
+	assert.Equal([]string{"", "pageSize=10&sort=name&startIndex=20"}, queries)
+}
@@ -0,0 +1,76 @@
+package hateoas
+
+import (
+	"context"
+	"iter"
+)
+
+// PagedResource is implemented by collection resources whose pages are linked with "next"
+type PagedResource interface {
+	PageLinks() Links
+}
+
+// Page is a PagedResource which also exposes its items
+type Page[T any] interface {
+	PagedResource
+	PageItems() []T
+}
+
+// Pages returns an iterator over each page of a collection. The first page is found as per
+// Client.Do, starting at `url` and traversing `navigateLinks`, after which "next" links are
+// followed until there are none. Iteration stops early when the caller breaks out of the loop,
+// or after yielding the first error (including ctx being cancelled).
+//
+// Query and template options only apply to the first page, as "next" links carry their own;
+// the rest (e.g. WithTrace) apply to every page.
+//
+//	for page, err := range hateoas.Pages[Things](ctx, client, "", hateoas.Navigate{"things"}) {
+func Pages[P any, PP interface {
+	*P
+	PagedResource
+}](ctx context.Context, c *Client, url string, navigateLinks Navigate, opts ...RequestOption) iter.Seq2[PP, error] {
+	return func(yield func(PP, error) bool) {
+		for {
+			page := PP(new(P))
+			_, err := c.GetContext(ctx, url, navigateLinks, nil, nil, page, opts...)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(page, nil) {
+				return
+			}
+
+			next, err := page.PageLinks().Get("next")
+			if err != nil {
+				return
+			}
+			url = next.Href
+			navigateLinks = nil
+			opts = withoutURLOptions(opts)
+		}
+	}
+}
+
+// Items is the same as Pages, but yields each item of each page in turn
+//
+//	for thing, err := range hateoas.Items[Thing, Things](ctx, client, "", hateoas.Navigate{"things"}) {
+func Items[T any, P any, PP interface {
+	*P
+	Page[T]
+}](ctx context.Context, c *Client, url string, navigateLinks Navigate, opts ...RequestOption) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for page, err := range Pages[P, PP](ctx, c, url, navigateLinks, opts...) {
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range page.PageItems() {
+				if !yield(item, nil) {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package hateoas
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testPage struct {
+	Items []Bob `json:"Items"`
+	Links Links `json:"Links"`
+}
+
+func (p *testPage) PageLinks() Links { return p.Links }
+func (p *testPage) PageItems() []Bob { return p.Items }
+
+// createPagedServer serves `pages` pages of 2 items each from /things?page=N
+func createPagedServer(pages int) (*httptest.Server, *int) {
+	hits := 0
+	var ps *httptest.Server
+	ps = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if r.URL.Path == "/" {
+			fmt.Fprintf(w, `{"Links":[{"rel":"things","href":"/things?page=0"}]}`)
+			return
+		}
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		next := ""
+		if page+1 < pages {
+			next = fmt.Sprintf(`{"rel":"next","href":"/things?page=%d"}`, page+1)
+		}
+		fmt.Fprintf(w, `{"Items":[{"Name":"%d"},{"Name":"%d"}],"Links":[%s]}`, page*2, page*2+1, next)
+	}))
+	return ps, &hits
+}
+
+func TestPager(t *testing.T) {
+	assert := assert.New(t)
+
+	ps, hits := createPagedServer(3)
+	defer ps.Close()
+
+	client := Create(&Client{
+		EntryURL: ps.URL,
+	})
+
+	names := []string{}
+	for bob, err := range Items[Bob, testPage](context.Background(), client, "", Navigate{"things"}) {
+		assert.Nil(err)
+		names = append(names, bob.Name)
+	}
+	assert.Equal([]string{"0", "1", "2", "3", "4", "5"}, names)
+	assert.Equal(4, *hits)
+
+	// breaking out early stops fetching
+	*hits = 0
+	pages := 0
+	for page, err := range Pages[testPage](context.Background(), client, "", Navigate{"things"}) {
+		assert.Nil(err)
+		assert.Equal(2, len(page.Items))
+		pages++
+		if pages == 2 {
+			break
+		}
+	}
+	assert.Equal(3, *hits)
+}
+
+func TestPagerQuery(t *testing.T) {
+	assert := assert.New(t)
+
+	queries := []url.Values{}
+	ps := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, `{"Links":[{"rel":"things","href":"/things"}]}`)
+			return
+		}
+		query := r.URL.Query()
+		queries = append(queries, query)
+		start, _ := strconv.Atoi(query.Get("startIndex"))
+		next := ""
+		if start+2 < 6 {
+			next = fmt.Sprintf(`{"rel":"next","href":"/things?startIndex=%d&pageSize=2"}`, start+2)
+		}
+		fmt.Fprintf(w, `{"Items":[{"Name":"%d"},{"Name":"%d"}],"Links":[%s]}`, start, start+1, next)
+	}))
+	defer ps.Close()
+
+	client := Create(&Client{
+		EntryURL: ps.URL,
+	})
+
+	trace := &Trace{}
+	names := []string{}
+	query := url.Values{"startIndex": {"0"}, "pageSize": {"2"}}
+	for bob, err := range Items[Bob, testPage](context.Background(), client, "", Navigate{"things"}, WithQuery(query), WithTrace(trace)) {
+		assert.Nil(err)
+		names = append(names, bob.Name)
+	}
+	assert.Equal([]string{"0", "1", "2", "3", "4", "5"}, names)
+
+	// next links are followed as they are, without a second copy of the query
+	assert.Equal([]url.Values{
+		{"startIndex": {"0"}, "pageSize": {"2"}},
+		{"startIndex": {"2"}, "pageSize": {"2"}},
+		{"startIndex": {"4"}, "pageSize": {"2"}},
+	}, queries)
+	assert.Equal(ps.URL+"/things?startIndex=4&pageSize=2", trace.URL)
+}
+
+func TestPagerErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	ps, _ := createPagedServer(3)
+	defer ps.Close()
+
+	client := Create(&Client{
+		EntryURL: ps.URL,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	count := 0
+	var lastErr error
+	for _, err := range Items[Bob, testPage](ctx, client, "", Navigate{"things"}) {
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		count++
+		cancel()
+	}
+	assert.ErrorIs(lastErr, context.Canceled)
+	assert.Equal(2, count)
+
+	for _, err := range Items[Bob, testPage](context.Background(), client, "", Navigate{"nothing"}) {
+		assert.True(IsLinkNotFound(err))
+	}
+}
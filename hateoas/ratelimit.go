@@ -0,0 +1,195 @@
+package hateoas
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimit is a token bucket allowing Rate requests per second on average, in
+// bursts of up to Burst. A zero Rate is unlimited.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// DefaultMethodClass puts safe methods in the "read" class and everything else in "write"
+func DefaultMethodClass(method string) string {
+	switch method {
+	case "GET", "HEAD", "OPTIONS", "TRACE":
+		return "read"
+	}
+	return "write"
+}
+
+// RateLimitDoer is a HTTPDoer which limits requests with a token bucket per host and
+// method class, blocking callers until there is capacity or the request's context is done.
+//
+// With AdaptToServer set, a bucket is also paused when the server says so: by a
+// Retry-After on a 429 or 503, or by X-RateLimit-Remaining reaching 0 before
+// X-RateLimit-Reset (either seconds from now, or a unix time).
+type RateLimitDoer struct {
+	Http HTTPDoer
+
+	// Limit applies to any method class missing from Limits
+	Limit  RateLimit
+	Limits map[string]RateLimit
+
+	// MethodClass defaults to DefaultMethodClass
+	MethodClass func(method string) string
+
+	AdaptToServer bool
+
+	mu      sync.Mutex
+	buckets map[bucketKey]*bucket
+}
+
+type bucketKey struct {
+	host  string
+	class string
+}
+
+type bucket struct {
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+}
+
+// CreateRateLimitDoer wraps `doer` (defaulting to a plain *http.Client) with `limit`
+// for every host and method class, adapting to the server's rate limit headers
+func CreateRateLimitDoer(doer HTTPDoer, limit RateLimit) *RateLimitDoer {
+	if doer == nil {
+		doer = &http.Client{}
+	}
+	return &RateLimitDoer{
+		Http:          doer,
+		Limit:         limit,
+		AdaptToServer: true,
+	}
+}
+
+func (r *RateLimitDoer) limit(class string) RateLimit {
+	if limit, ok := r.Limits[class]; ok {
+		return limit
+	}
+	return r.Limit
+}
+
+// bucket must be called with r.mu held
+func (r *RateLimitDoer) bucket(key bucketKey) *bucket {
+	if r.buckets == nil {
+		r.buckets = map[bucketKey]*bucket{}
+	}
+	b, ok := r.buckets[key]
+	if !ok {
+		b = &bucket{tokens: math.Max(float64(r.limit(key.class).Burst), 1), last: time.Now()}
+		r.buckets[key] = b
+	}
+	return b
+}
+
+// reserve takes a token if one is available, otherwise returns how long to wait before trying again
+func (r *RateLimitDoer) reserve(key bucketKey) time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	limit := r.limit(key.class)
+	b := r.bucket(key)
+	now := time.Now()
+	if now.Before(b.pausedUntil) {
+		return b.pausedUntil.Sub(now)
+	}
+	if limit.Rate <= 0 {
+		return 0
+	}
+
+	burst := math.Max(float64(limit.Burst), 1)
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+}
+
+// Wait blocks until a request to `host` using `method` may be made, or `ctx` is done
+func (r *RateLimitDoer) Wait(ctx context.Context, host string, method string) error {
+	class := DefaultMethodClass
+	if r.MethodClass != nil {
+		class = r.MethodClass
+	}
+	key := bucketKey{host, class(method)}
+
+	for {
+		wait := r.reserve(key)
+		if wait <= 0 {
+			return ctx.Err()
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// adapt pauses or drains the bucket according to the server's headers
+func (r *RateLimitDoer) adapt(key bucketKey, resp *http.Response) {
+	var until time.Time
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		if after, ok := retryAfter(resp); ok {
+			until = time.Now().Add(after)
+		}
+	}
+
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	hasRemaining := err == nil
+	if hasRemaining && remaining <= 0 {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			var when time.Time
+			if reset > 1000000000 {
+				when = time.Unix(reset, 0)
+			} else {
+				when = time.Now().Add(time.Duration(reset) * time.Second)
+			}
+			if when.After(until) {
+				until = when
+			}
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b := r.bucket(key)
+	if until.After(b.pausedUntil) {
+		b.pausedUntil = until
+	}
+	if hasRemaining && float64(remaining) < b.tokens {
+		b.tokens = math.Max(float64(remaining), 0)
+	}
+}
+
+// Do implements HTTPDoer
+func (r *RateLimitDoer) Do(req *http.Request) (*http.Response, error) {
+	err := r.Wait(req.Context(), req.URL.Host, req.Method)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.Http.Do(req)
+	if err == nil && r.AdaptToServer {
+		class := DefaultMethodClass
+		if r.MethodClass != nil {
+			class = r.MethodClass
+		}
+		r.adapt(bucketKey{req.URL.Host, class(req.Method)}, resp)
+	}
+	return resp, err
+}
@@ -0,0 +1,84 @@
+package hateoas
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func rateLimitedRequest(ctx context.Context, doer HTTPDoer, method string, url string) error {
+	req, _ := http.NewRequestWithContext(ctx, method, url, nil)
+	_, err := doer.Do(req)
+	return err
+}
+
+func TestRateLimitDoer(t *testing.T) {
+	assert := assert.New(t)
+
+	hits := 0
+	r := CreateRateLimitDoer(DoerFunc(func(req *http.Request) (*http.Response, error) {
+		hits++
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Request: req}, nil
+	}), RateLimit{Rate: 50, Burst: 2})
+	ctx := context.Background()
+
+	// the burst is immediate, then 20ms per request
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		assert.Nil(rateLimitedRequest(ctx, r, "GET", "http://a/"))
+	}
+	assert.True(time.Since(start) >= 30*time.Millisecond)
+	assert.Equal(4, hits)
+
+	// other hosts and method classes have their own buckets
+	r.Limits = map[string]RateLimit{"write": {Rate: 1, Burst: 1}}
+	start = time.Now()
+	assert.Nil(rateLimitedRequest(ctx, r, "GET", "http://b/"))
+	assert.Nil(rateLimitedRequest(ctx, r, "POST", "http://a/"))
+	assert.True(time.Since(start) < 20*time.Millisecond)
+
+	// which block until the context is done
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	assert.Equal(context.DeadlineExceeded, rateLimitedRequest(timeout, r, "DELETE", "http://a/"))
+	assert.Equal(6, hits)
+
+	r = CreateRateLimitDoer(r.Http, RateLimit{})
+	for i := 0; i < 100; i++ {
+		assert.Nil(rateLimitedRequest(ctx, r, "GET", "http://a/"))
+	}
+}
+
+func TestRateLimitDoerAdapts(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, headers := range [][]string{
+		{"Retry-After", "1"},
+		{"X-RateLimit-Remaining", "0", "X-RateLimit-Reset", "1"},
+		{"X-RateLimit-Remaining", "0", "X-RateLimit-Reset", "4102444800"},
+	} {
+		header := http.Header{}
+		for i := 0; i < len(headers); i += 2 {
+			header.Set(headers[i], headers[i+1])
+		}
+		hits := 0
+		r := CreateRateLimitDoer(DoerFunc(func(req *http.Request) (*http.Response, error) {
+			hits++
+			return &http.Response{StatusCode: http.StatusTooManyRequests, Header: header, Request: req}, nil
+		}), RateLimit{Rate: 1000, Burst: 10})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		assert.Nil(rateLimitedRequest(ctx, r, "GET", "http://a/"))
+		assert.Equal(context.DeadlineExceeded, rateLimitedRequest(ctx, r, "GET", "http://a/"), "%v", header)
+		assert.Equal(1, hits)
+		cancel()
+
+		r.AdaptToServer = false
+		r.buckets = nil
+		assert.Nil(rateLimitedRequest(context.Background(), r, "GET", "http://a/"))
+		assert.Nil(rateLimitedRequest(context.Background(), r, "GET", "http://a/"))
+	}
+}
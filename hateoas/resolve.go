@@ -0,0 +1,97 @@
+package hateoas
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// ResolveHref resolves `href` against `base` as per RFC 3986, so that relative
+// links work behind reverse proxies etc. Absolute hrefs are returned unchanged,
+// as is `href` if either fails to parse.
+func ResolveHref(base string, href string) string {
+	b, err := url.Parse(base)
+	if err != nil {
+		return href
+	}
+	return resolveHref(b, href)
+}
+
+func resolveHref(base *url.URL, href string) string {
+	if base == nil {
+		return href
+	}
+	// only the literal part of a template is resolved, as parsing would escape
+	// the braces; one starting with an expression could expand to anything
+	template := ""
+	if open := strings.Index(href, "{"); open >= 0 {
+		href, template = href[:open], href[open:]
+		if href == "" {
+			return template
+		}
+	}
+	h, err := url.Parse(href)
+	if err != nil {
+		return href + template
+	}
+	return base.ResolveReference(h).String() + template
+}
+
+// Resolve returns a copy of the link with Href resolved against `base`
+func (l Link) Resolve(base string) Link {
+	l.Href = ResolveHref(base, l.Href)
+	return l
+}
+
+// Resolve returns a copy of the links with every Href resolved against `base`
+func (l Links) Resolve(base string) Links {
+	result := make(Links, len(l))
+	for i := range l {
+		result[i] = l[i].Resolve(base)
+	}
+	return result
+}
+
+var linkType = reflect.TypeOf(Link{})
+
+// resolveLinks walks a decoded response, resolving every Link (and so Links) it
+// finds against `base`. Untyped JSON (e.g. map[string]interface{}) is left alone,
+// as there is no telling which "href" values are links.
+func resolveLinks(base *url.URL, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			resolveLinks(base, v.Elem())
+		}
+	case reflect.Struct:
+		if v.Type() == linkType {
+			if v.CanSet() {
+				href := v.FieldByName("Href")
+				href.SetString(resolveHref(base, href.String()))
+			}
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath == "" {
+				resolveLinks(base, v.Field(i))
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			// raw bytes, e.g. json.RawMessage
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			resolveLinks(base, v.Index(i))
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return
+		}
+		// map values are not addressable, so only *Link and the like are resolved
+		iter := v.MapRange()
+		for iter.Next() {
+			resolveLinks(base, iter.Value())
+		}
+	}
+}
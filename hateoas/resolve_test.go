@@ -0,0 +1,71 @@
+package hateoas
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveHref(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("http://a/b/c/g", ResolveHref("http://a/b/c/d;p?q", "g"))
+	assert.Equal("http://a/g", ResolveHref("http://a/b/c/d;p?q", "/g"))
+	assert.Equal("http://g", ResolveHref("http://a/b/c/d;p?q", "//g"))
+	assert.Equal("http://a/b/g", ResolveHref("http://a/b/c/d;p?q", "../g"))
+	assert.Equal("http://a/b/c/d;p?y", ResolveHref("http://a/b/c/d;p?q", "?y"))
+	assert.Equal("https://elsewhere/x", ResolveHref("http://a/b/c/d;p?q", "https://elsewhere/x"))
+
+	// only the literal part of a template is resolved
+	assert.Equal("http://a/clients{?startIndex}", ResolveHref("http://a/b/c/d;p?q", "/clients{?startIndex}"))
+	assert.Equal("http://a/b/c/people/{id}", ResolveHref("http://a/b/c/d;p?q", "people/{id}"))
+	assert.Equal("{+path}", ResolveHref("http://a/b/c/d;p?q", "{+path}"))
+
+	links := Links{{Rel: "self", Href: "bob"}}.Resolve("http://a/api/")
+	assert.Equal("http://a/api/bob", links.Self())
+}
+
+func TestRelativeLinks(t *testing.T) {
+	assert := assert.New(t)
+
+	rs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/":
+			fmt.Fprint(w, `{"Links":[{"rel":"bob","href":"people/bob"}]}`)
+		case "/api/people/bob":
+			fmt.Fprint(w, `{"Name":"bob","Links":[{"rel":"where","href":"../where"},{"rel":"self","href":"/api/people/bob"}]}`)
+		case "/api/where":
+			fmt.Fprint(w, `{"Where":"over the rainbow","Links":[{"rel":"self","href":"where"}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer rs.Close()
+
+	client := Create(&Client{
+		EntryURL: rs.URL + "/api/",
+	})
+
+	var where Where
+	_, err := client.Get("", Navigate{"bob", "where"}, nil, nil, &where)
+	assert.Nil(err)
+	assert.Equal("over the rainbow", where.Where)
+
+	var bob Bob
+	_, err = client.Get("", Navigate{"bob"}, nil, nil, &bob)
+	assert.Nil(err)
+	assert.Equal(rs.URL+"/api/people/bob", bob.Links.Self())
+	whereLink, err := bob.Links.Get("where")
+	assert.Nil(err)
+	assert.Equal(rs.URL+"/api/where", whereLink.Href)
+
+	// untyped documents are left as returned
+	var untyped map[string]interface{}
+	_, err = client.Get("", Navigate{"bob", "where"}, nil, nil, &untyped)
+	assert.Nil(err)
+	self := untyped["Links"].([]interface{})[0].(map[string]interface{})
+	assert.Equal("where", self["href"])
+}
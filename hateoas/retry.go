@@ -0,0 +1,158 @@
+package hateoas
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryDoer is a HTTPDoer which retries requests failing with a connection
+// error, a 5xx or a 429, using exponential backoff with full jitter. A
+// Retry-After response header takes precedence over the computed backoff,
+// unless it asks for a longer wait than MaxRetryAfter in which case the
+// response is returned as is.
+//
+// Only idempotent methods are retried unless RetryNonIdempotent is set.
+// Request bodies are replayed using Request.GetBody, and any body without
+// one is buffered in memory first.
+type RetryDoer struct {
+	Http HTTPDoer
+
+	// MaxRetries is the number of retries after the first attempt
+	MaxRetries int
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// MaxRetryAfter is the longest Retry-After honoured, zero for no limit
+	MaxRetryAfter time.Duration
+
+	// RetryNonIdempotent permits retrying POST and PATCH
+	RetryNonIdempotent bool
+}
+
+// CreateRetryDoer wraps `doer` (defaulting to a plain *http.Client) with 3 retries,
+// backing off between 100ms and 10s and waiting at most a minute for Retry-After
+func CreateRetryDoer(doer HTTPDoer) *RetryDoer {
+	if doer == nil {
+		doer = &http.Client{}
+	}
+	return &RetryDoer{
+		Http:          doer,
+		MaxRetries:    3,
+		MinBackoff:    100 * time.Millisecond,
+		MaxBackoff:    10 * time.Second,
+		MaxRetryAfter: time.Minute,
+	}
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS", "TRACE", "PUT", "DELETE":
+		return true
+	}
+	return false
+}
+
+func retryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests ||
+		(statusCode >= http.StatusInternalServerError && statusCode != http.StatusNotImplemented)
+}
+
+// retryAfter parses a Retry-After header, which is either seconds or a HTTP date
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if when, err := http.ParseTime(value); err == nil {
+		wait := time.Until(when)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return 0, false
+}
+
+func (r *RetryDoer) backoff(attempt int) time.Duration {
+	ceiling := r.MinBackoff << uint(attempt)
+	if ceiling <= 0 || (r.MaxBackoff > 0 && ceiling > r.MaxBackoff) {
+		ceiling = r.MaxBackoff
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// Do implements HTTPDoer
+func (r *RetryDoer) Do(req *http.Request) (*http.Response, error) {
+	if !idempotent(req.Method) && !r.RetryNonIdempotent {
+		return r.Http.Do(req)
+	}
+
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		buf, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(buf))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(buf)), nil
+		}
+	}
+
+	ctx := req.Context()
+	attempt := req
+	for i := 0; ; i++ {
+		resp, err := r.Http.Do(attempt)
+		if i >= r.MaxRetries || ctx.Err() != nil {
+			return resp, err
+		}
+		if err == nil && !retryableStatus(resp.StatusCode) {
+			return resp, err
+		}
+
+		wait := r.backoff(i)
+		if after, ok := retryAfter(resp); ok {
+			if r.MaxRetryAfter > 0 && after > r.MaxRetryAfter {
+				return resp, err
+			}
+			wait = after
+		}
+		if resp != nil {
+			ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		attempt = req.Clone(ctx)
+		if req.GetBody != nil {
+			attempt.Body, err = req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+}
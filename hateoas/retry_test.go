@@ -0,0 +1,186 @@
+package hateoas
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type flakyServer struct {
+	*httptest.Server
+	failures   int
+	status     int
+	retryAfter string
+	hits       int
+	bodies     []string
+}
+
+func createFlakyServer(failures int, status int) *flakyServer {
+	fs := &flakyServer{failures: failures, status: status}
+	fs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fs.hits++
+		buf, _ := ioutil.ReadAll(r.Body)
+		fs.bodies = append(fs.bodies, string(buf))
+		if fs.hits <= fs.failures {
+			if fs.retryAfter != "" {
+				w.Header().Set("Retry-After", fs.retryAfter)
+			}
+			w.WriteHeader(fs.status)
+			return
+		}
+		w.Write([]byte(`{"Where":"over the rainbow"}`))
+	}))
+	return fs
+}
+
+func createTestRetryDoer() *RetryDoer {
+	r := CreateRetryDoer(nil)
+	r.MinBackoff = time.Millisecond
+	r.MaxBackoff = 5 * time.Millisecond
+	return r
+}
+
+func TestRetryDoer(t *testing.T) {
+	assert := assert.New(t)
+
+	fs := createFlakyServer(2, http.StatusServiceUnavailable)
+	defer fs.Close()
+
+	client := Create(&Client{
+		EntryURL: fs.URL,
+		Http:     createTestRetryDoer(),
+	})
+
+	var where Where
+	_, err := client.Get("", nil, nil, nil, &where)
+	assert.Nil(err)
+	assert.Equal("over the rainbow", where.Where)
+	assert.Equal(3, fs.hits)
+}
+
+func TestRetryDoerGivesUp(t *testing.T) {
+	assert := assert.New(t)
+
+	fs := createFlakyServer(10, http.StatusBadGateway)
+	defer fs.Close()
+
+	client := Create(&Client{
+		EntryURL: fs.URL,
+		Http:     createTestRetryDoer(),
+	})
+
+	_, err := client.Get("", nil, nil, nil, nil)
+	assert.True(IsStatus(err, http.StatusBadGateway))
+	assert.Equal(4, fs.hits)
+}
+
+func TestRetryDoerNotRetryable(t *testing.T) {
+	assert := assert.New(t)
+
+	fs := createFlakyServer(1, http.StatusNotFound)
+	defer fs.Close()
+
+	client := Create(&Client{
+		EntryURL: fs.URL,
+		Http:     createTestRetryDoer(),
+	})
+
+	_, err := client.Get("", nil, nil, nil, nil)
+	assert.True(IsNotFound(err))
+	assert.Equal(1, fs.hits)
+}
+
+func TestRetryDoerPost(t *testing.T) {
+	assert := assert.New(t)
+
+	fs := createFlakyServer(1, http.StatusTooManyRequests)
+	defer fs.Close()
+
+	retry := createTestRetryDoer()
+	client := Create(&Client{
+		EntryURL: fs.URL,
+		Http:     retry,
+	})
+
+	// not retried by default
+	_, err := client.Post("", nil, nil, strings.NewReader(`{"Name":"bob"}`), nil)
+	assert.True(IsStatus(err, http.StatusTooManyRequests))
+	assert.Equal(1, fs.hits)
+
+	// opted in, and the body is replayed, even from a plain io.Reader
+	fs.hits = 0
+	fs.bodies = nil
+	retry.RetryNonIdempotent = true
+	_, err = client.Post("", nil, nil, ioutil.NopCloser(strings.NewReader(`{"Name":"bob"}`)), nil)
+	assert.Nil(err)
+	assert.Equal(2, fs.hits)
+	assert.Equal([]string{`{"Name":"bob"}`, `{"Name":"bob"}`}, fs.bodies)
+}
+
+func TestRetryDoerRetryAfter(t *testing.T) {
+	assert := assert.New(t)
+
+	fs := createFlakyServer(1, http.StatusServiceUnavailable)
+	fs.retryAfter = "1"
+	defer fs.Close()
+
+	client := Create(&Client{
+		EntryURL: fs.URL,
+		Http:     createTestRetryDoer(),
+	})
+
+	start := time.Now()
+	_, err := client.Get("", nil, nil, nil, nil)
+	assert.Nil(err)
+	assert.True(time.Since(start) >= time.Second)
+
+	// cancelled whilst waiting
+	fs.hits = 0
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = client.GetContext(ctx, "", nil, nil, nil, nil)
+	assert.ErrorIs(err, context.DeadlineExceeded)
+	assert.Equal(1, fs.hits)
+
+	// longer than MaxRetryAfter returns the response rather than waiting
+	fs.hits = 0
+	fs.retryAfter = "86400"
+	doer := createTestRetryDoer()
+	doer.MaxRetryAfter = time.Second
+	req, _ := http.NewRequest("GET", fs.URL, nil)
+	start = time.Now()
+	resp, err := doer.Do(req)
+	assert.Nil(err)
+	assert.Equal(http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal("86400", resp.Header.Get("Retry-After"))
+	assert.Equal(1, fs.hits)
+	assert.True(time.Since(start) < time.Second)
+}
+
+func TestRetryAfter(t *testing.T) {
+	assert := assert.New(t)
+
+	resp := &http.Response{Header: http.Header{}}
+	_, ok := retryAfter(resp)
+	assert.False(ok)
+
+	resp.Header.Set("Retry-After", "5")
+	wait, ok := retryAfter(resp)
+	assert.True(ok)
+	assert.Equal(5*time.Second, wait)
+
+	resp.Header.Set("Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	wait, ok = retryAfter(resp)
+	assert.True(ok)
+	assert.True(wait > 59*time.Minute)
+
+	resp.Header.Set("Retry-After", "soon")
+	_, ok = retryAfter(resp)
+	assert.False(ok)
+}
@@ -0,0 +1,48 @@
+package hateoas
+
+// TraceHop is a single document visited by Client.Do
+type TraceHop struct {
+	URL string
+	// StatusCode is 0 for documents which were embedded in their parent rather than fetched
+	StatusCode int
+	Embedded   bool
+	// Rels are the links the document offered
+	Rels []string
+}
+
+// Trace records the path taken by a Client.Do call, see WithTrace
+type Trace struct {
+	// FromCache is how many of the navigated links were resolved by the LinkCache
+	FromCache int
+	Hops      []TraceHop
+	// URL is the final URL, which the terminal request was (or in a dry run, would be) issued to
+	URL string
+}
+
+func (t *Trace) hop(url string, statusCode int, embedded bool, links Links) {
+	if t == nil {
+		return
+	}
+	t.Hops = append(t.Hops, TraceHop{
+		URL:        url,
+		StatusCode: statusCode,
+		Embedded:   embedded,
+		Rels:       links.Rels(),
+	})
+}
+
+// WithTrace fills in `trace` with each hop of the call, including the terminal request
+// (whose Rels are only known when its response has links)
+func WithTrace(trace *Trace) RequestOption {
+	return func(o *requestOptions) {
+		o.trace = trace
+	}
+}
+
+// WithDryRun navigates as usual, but rather than issuing the terminal request just
+// stores its URL in `target`. Do then returns a nil response and error.
+func WithDryRun(target *string) RequestOption {
+	return func(o *requestOptions) {
+		o.dryRun = target
+	}
+}
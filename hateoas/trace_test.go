@@ -0,0 +1,68 @@
+package hateoas
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrace(t *testing.T) {
+	assert := assert.New(t)
+
+	hits := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte(`{"Links":[{"rel":"clients","href":"/clients"},{"rel":"versions","href":"/versions"}]}`))
+		case "/clients":
+			w.Write([]byte(`{"Links":[{"rel":"self","href":"/clients"}],"Items":[]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	client := Create(&Client{EntryURL: ts.URL})
+
+	var trace Trace
+	_, err := client.Get("", Navigate{"clients"}, nil, nil, nil, WithTrace(&trace))
+	assert.Nil(err)
+	assert.Equal(ts.URL+"/clients", trace.URL)
+	assert.Equal([]TraceHop{
+		{URL: ts.URL, StatusCode: http.StatusOK, Rels: []string{"clients", "versions"}},
+		{URL: ts.URL + "/clients", StatusCode: http.StatusOK, Rels: []string{"self"}},
+	}, trace.Hops)
+
+	// a missing rel says where, and what was there instead
+	_, err = client.Get("", Navigate{"clients", "accesskeys"}, nil, nil, nil, WithTrace(&trace))
+	assert.True(IsLinkNotFound(err))
+	assert.True(IsLinkNotFound(errors.Wrap(err, "wrapped")))
+	var lerr *LinkNotFoundError
+	assert.True(errors.As(err, &lerr))
+	assert.Equal("accesskeys", lerr.Rel)
+	assert.Equal(1, lerr.Hop)
+	assert.Equal(ts.URL+"/clients", lerr.URL)
+	assert.Equal([]string{"self"}, lerr.Available)
+	assert.Contains(err.Error(), `"accesskeys" (hop 1) at `+ts.URL+"/clients")
+	assert.Len(trace.Hops, 2)
+	assert.Equal("", trace.URL)
+
+	// failing hops are recorded too
+	_, err = client.Get("", Navigate{"versions", "latest"}, nil, nil, nil, WithTrace(&trace))
+	assert.True(IsNotFound(err))
+	assert.Len(trace.Hops, 2)
+	assert.Equal(http.StatusNotFound, trace.Hops[1].StatusCode)
+
+	// a dry run stops short of the terminal request
+	hits = 0
+	var target string
+	resp, err := client.Delete("", Navigate{"clients"}, nil, nil, nil, WithDryRun(&target))
+	assert.Nil(err)
+	assert.Nil(resp)
+	assert.Equal(ts.URL+"/clients", target)
+	assert.Equal(1, hits)
+}
@@ -0,0 +1,249 @@
+package hateoas
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrorBadTemplate = "bad URI template"
+)
+
+// TemplateVars holds the variables for expanding a RFC 6570 URI template. Values may be
+// strings (or anything fmt.Sprint can format), lists ([]string, []interface{}) or
+// associative arrays (map[string]string, map[string]interface{}).
+type TemplateVars map[string]interface{}
+
+// Expand expands the link's href as a URI template
+func (l Link) Expand(vars TemplateVars) (string, error) {
+	return ExpandTemplate(l.Href, vars)
+}
+
+type templateOperator struct {
+	first    string
+	sep      string
+	named    bool
+	ifEmpty  string
+	reserved bool
+}
+
+var templateOperators = map[byte]templateOperator{
+	'+': {first: "", sep: ",", reserved: true},
+	'#': {first: "#", sep: ",", reserved: true},
+	'.': {first: ".", sep: "."},
+	'/': {first: "/", sep: "/"},
+	';': {first: ";", sep: ";", named: true},
+	'?': {first: "?", sep: "&", named: true, ifEmpty: "="},
+	'&': {first: "&", sep: "&", named: true, ifEmpty: "="},
+}
+
+// ExpandTemplate expands a RFC 6570 (up to level 4) URI template
+func ExpandTemplate(template string, vars TemplateVars) (string, error) {
+	var result strings.Builder
+	for {
+		open := strings.IndexByte(template, '{')
+		if open < 0 {
+			if strings.IndexByte(template, '}') >= 0 {
+				return "", errors.New(ErrorBadTemplate)
+			}
+			result.WriteString(template)
+			return result.String(), nil
+		}
+		close := strings.IndexByte(template[open:], '}')
+		if close < 0 {
+			return "", errors.New(ErrorBadTemplate)
+		}
+		result.WriteString(template[:open])
+		expanded, err := expandExpression(template[open+1:open+close], vars)
+		if err != nil {
+			return "", err
+		}
+		result.WriteString(expanded)
+		template = template[open+close+1:]
+	}
+}
+
+func expandExpression(expr string, vars TemplateVars) (string, error) {
+	if expr == "" {
+		return "", errors.New(ErrorBadTemplate)
+	}
+	op, exists := templateOperators[expr[0]]
+	if exists {
+		expr = expr[1:]
+	} else {
+		op = templateOperator{sep: ","}
+	}
+
+	var result strings.Builder
+	first := true
+	for _, spec := range strings.Split(expr, ",") {
+		name, explode, prefix, err := parseVarSpec(spec)
+		if err != nil {
+			return "", err
+		}
+		expanded, defined := expandValue(op, name, vars[name], explode, prefix)
+		if !defined {
+			continue
+		}
+		if first {
+			result.WriteString(op.first)
+			first = false
+		} else {
+			result.WriteString(op.sep)
+		}
+		result.WriteString(expanded)
+	}
+	return result.String(), nil
+}
+
+func parseVarSpec(spec string) (name string, explode bool, prefix int, err error) {
+	if strings.HasSuffix(spec, "*") {
+		explode = true
+		spec = spec[:len(spec)-1]
+	} else if i := strings.IndexByte(spec, ':'); i >= 0 {
+		_, err = fmt.Sscanf(spec[i+1:], "%d", &prefix)
+		if err != nil || prefix <= 0 || prefix >= 10000 {
+			return "", false, 0, errors.New(ErrorBadTemplate)
+		}
+		spec = spec[:i]
+	}
+	if spec == "" {
+		return "", false, 0, errors.New(ErrorBadTemplate)
+	}
+	return spec, explode, prefix, nil
+}
+
+// templateValue normalises a variable into a string, list or sorted associative array
+func templateValue(value interface{}) (s *string, list []string, keys []string, assoc map[string]string) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil, nil, nil
+	case string:
+		return &v, nil, nil, nil
+	case []string:
+		return nil, v, nil, nil
+	case []interface{}:
+		for _, item := range v {
+			list = append(list, fmt.Sprint(item))
+		}
+		return nil, list, nil, nil
+	case map[string]string:
+		assoc = v
+	case map[string]interface{}:
+		assoc = map[string]string{}
+		for k, item := range v {
+			assoc[k] = fmt.Sprint(item)
+		}
+	default:
+		str := fmt.Sprint(v)
+		return &str, nil, nil, nil
+	}
+	for k := range assoc {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return nil, nil, keys, assoc
+}
+
+func expandValue(op templateOperator, name string, value interface{}, explode bool, prefix int) (string, bool) {
+	s, list, keys, assoc := templateValue(value)
+	encode := func(v string) string {
+		return templateEncode(v, op.reserved)
+	}
+	named := func(v string) string {
+		if v == "" {
+			return name + op.ifEmpty
+		}
+		return name + "=" + v
+	}
+
+	switch {
+	case s != nil:
+		v := *s
+		if prefix > 0 {
+			runes := []rune(v)
+			if len(runes) > prefix {
+				v = string(runes[:prefix])
+			}
+		}
+		if op.named {
+			return named(encode(v)), true
+		}
+		return encode(v), true
+
+	case list != nil:
+		if len(list) == 0 {
+			return "", false
+		}
+		parts := []string{}
+		for _, item := range list {
+			if explode && op.named {
+				parts = append(parts, named(encode(item)))
+			} else {
+				parts = append(parts, encode(item))
+			}
+		}
+		if explode {
+			return strings.Join(parts, op.sep), true
+		}
+		if op.named {
+			return named(strings.Join(parts, ",")), true
+		}
+		return strings.Join(parts, ","), true
+
+	case assoc != nil:
+		if len(assoc) == 0 {
+			return "", false
+		}
+		parts := []string{}
+		for _, k := range keys {
+			if explode {
+				if op.named && assoc[k] == "" {
+					parts = append(parts, encode(k)+op.ifEmpty)
+				} else {
+					parts = append(parts, encode(k)+"="+encode(assoc[k]))
+				}
+			} else {
+				parts = append(parts, encode(k), encode(assoc[k]))
+			}
+		}
+		if explode {
+			return strings.Join(parts, op.sep), true
+		}
+		if op.named {
+			return named(strings.Join(parts, ",")), true
+		}
+		return strings.Join(parts, ","), true
+	}
+	return "", false
+}
+
+const templateUnreserved = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-._~"
+const templateReserved = ":/?#[]@!$&'()*+,;="
+
+// templateEncode percent-encodes everything outside of the allowed set. With
+// `reserved`, reserved characters and existing pct-encoded triplets pass through.
+func templateEncode(s string, reserved bool) string {
+	var result strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case strings.IndexByte(templateUnreserved, c) >= 0:
+			result.WriteByte(c)
+		case reserved && strings.IndexByte(templateReserved, c) >= 0:
+			result.WriteByte(c)
+		case reserved && c == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]):
+			result.WriteByte(c)
+		default:
+			fmt.Fprintf(&result, "%%%02X", c)
+		}
+	}
+	return result.String()
+}
+
+func isHex(c byte) bool {
+	return ('0' <= c && c <= '9') || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
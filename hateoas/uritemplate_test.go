@@ -0,0 +1,155 @@
+package hateoas
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// examples from RFC 6570 section 3.2
+func TestExpandTemplate(t *testing.T) {
+	assert := assert.New(t)
+
+	vars := TemplateVars{
+		"count":      []string{"one", "two", "three"},
+		"dom":        []string{"example", "com"},
+		"dub":        "me/too",
+		"hello":      "Hello World!",
+		"half":       "50%",
+		"var":        "value",
+		"who":        "fred",
+		"base":       "http://example.com/home/",
+		"path":       "/foo/bar",
+		"list":       []string{"red", "green", "blue"},
+		"keys":       map[string]string{"semi": ";", "dot": ".", "comma": ","},
+		"v":          6,
+		"x":          1024,
+		"y":          768,
+		"empty":      "",
+		"empty_keys": map[string]string{},
+		"undef":      nil,
+	}
+
+	tests := map[string]string{
+		"{var}":              "value",
+		"{hello}":            "Hello%20World%21",
+		"{half}":             "50%25",
+		"O{empty}X":          "OX",
+		"O{undef}X":          "OX",
+		"{x,y}":              "1024,768",
+		"{x,hello,y}":        "1024,Hello%20World%21,768",
+		"?{x,empty}":         "?1024,",
+		"?{x,undef}":         "?1024",
+		"{var:3}":            "val",
+		"{var:30}":           "value",
+		"{list}":             "red,green,blue",
+		"{list*}":            "red,green,blue",
+		"{keys}":             "comma,%2C,dot,.,semi,%3B",
+		"{keys*}":            "comma=%2C,dot=.,semi=%3B",
+		"{+var}":             "value",
+		"{+hello}":           "Hello%20World!",
+		"{+half}":            "50%25",
+		"{base}index":        "http%3A%2F%2Fexample.com%2Fhome%2Findex",
+		"{+base}index":       "http://example.com/home/index",
+		"{+path}/here":       "/foo/bar/here",
+		"here?ref={+path}":   "here?ref=/foo/bar",
+		"{+path:6}/here":     "/foo/b/here",
+		"{+keys*}":           "comma=,,dot=.,semi=;",
+		"{#var}":             "#value",
+		"{#hello}":           "#Hello%20World!",
+		"{#path:6}/here":     "#/foo/b/here",
+		"{#list*}":           "#red,green,blue",
+		"X{.var}":            "X.value",
+		"X{.x,y}":            "X.1024.768",
+		"X{.list*}":          "X.red.green.blue",
+		"X{.empty_keys}":     "X",
+		"{/who}":             "/fred",
+		"{/who,who}":         "/fred/fred",
+		"{/half,who}":        "/50%25/fred",
+		"{/who,dub}":         "/fred/me%2Ftoo",
+		"{/var,x}/here":      "/value/1024/here",
+		"{/list*,path:4}":    "/red/green/blue/%2Ffoo",
+		"{;who}":             ";who=fred",
+		"{;half}":            ";half=50%25",
+		"{;empty}":           ";empty",
+		"{;v,empty,who}":     ";v=6;empty;who=fred",
+		"{;x,y,undef}":       ";x=1024;y=768",
+		"{;list}":            ";list=red,green,blue",
+		"{;list*}":           ";list=red;list=green;list=blue",
+		"{;keys*}":           ";comma=%2C;dot=.;semi=%3B",
+		"{?who}":             "?who=fred",
+		"{?half}":            "?half=50%25",
+		"{?x,y,empty}":       "?x=1024&y=768&empty=",
+		"{?x,y,undef}":       "?x=1024&y=768",
+		"{?var:3}":           "?var=val",
+		"{?list}":            "?list=red,green,blue",
+		"{?list*}":           "?list=red&list=green&list=blue",
+		"{?keys}":            "?keys=comma,%2C,dot,.,semi,%3B",
+		"{?keys*}":           "?comma=%2C&dot=.&semi=%3B",
+		"?fixed=yes{&x}":     "?fixed=yes&x=1024",
+		"{&x,y,empty}":       "&x=1024&y=768&empty=",
+		"{&list*}":           "&list=red&list=green&list=blue",
+		"{count}":            "one,two,three",
+		"{/count*}":          "/one/two/three",
+		"{?count*}":          "?count=one&count=two&count=three",
+		"www{.dom*}":         "www.example.com",
+		"/clients{?undef,v}": "/clients?v=6",
+	}
+	for template, expected := range tests {
+		expanded, err := ExpandTemplate(template, vars)
+		assert.Nil(err, template)
+		assert.Equal(expected, expanded, template)
+	}
+
+	for _, bad := range []string{"{var", "var}", "{}", "{var:0}", "{var:x}"} {
+		_, err := ExpandTemplate(bad, vars)
+		assert.NotNil(err, bad)
+	}
+}
+
+func TestTemplatedNavigate(t *testing.T) {
+	assert := assert.New(t)
+
+	us := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `{"Links":[{"rel":"person","href":"people/{id}","templated":true},{"rel":"people","href":"people{?pageSize}","templated":true}]}`)
+		case "/people":
+			fmt.Fprintf(w, `{"Name":"everyone %s"}`, r.URL.Query().Get("pageSize"))
+		default:
+			fmt.Fprintf(w, `{"Name":"%s"}`, r.URL.Path)
+		}
+	}))
+	defer us.Close()
+
+	client := Create(&Client{
+		EntryURL:  us.URL + "/",
+		LinkCache: CreateLinkCache(0),
+	})
+
+	var bob Bob
+	_, err := client.Get("", Navigate{"person"}, nil, nil, &bob, WithTemplateVars("person", TemplateVars{"id": "bob"}))
+	assert.Nil(err)
+	assert.Equal("/people/bob", bob.Name)
+
+	_, err = client.Get("", Navigate{"person"}, nil, nil, &bob, WithTemplateVars("person", TemplateVars{"id": "alice"}))
+	assert.Nil(err)
+	assert.Equal("/people/alice", bob.Name)
+	assert.Equal(0, client.LinkCache.Len())
+
+	_, err = client.Get("", Navigate{"people"}, nil, nil, &bob)
+	assert.Nil(err)
+	assert.Equal("everyone ", bob.Name)
+
+	_, err = client.Get("", Navigate{"people"}, nil, nil, &bob, WithTemplateVars("people", TemplateVars{"pageSize": 5}))
+	assert.Nil(err)
+	assert.Equal("everyone 5", bob.Name)
+
+	link := Link{Href: "/people/{id}", Templated: true}
+	href, err := link.Expand(TemplateVars{"id": "a b"})
+	assert.Nil(err)
+	assert.Equal("/people/a%20b", href)
+}
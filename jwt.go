@@ -1,22 +1,50 @@
 package deviceserver
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
 	"time"
 
+	"github.com/pkg/errors"
 	"github.com/square/go-jose"
 )
 
+var (
+	// ErrorUnsupportedAlgorithm is returned by JwtSigner.Init for algorithms other than
+	// HS256/384/512, RS256, PS256 and ES256
+	ErrorUnsupportedAlgorithm = "unsupported signing algorithm"
+	// ErrorKeyAlgorithmMismatch is returned by JwtSigner.Init when the key can't be used
+	// with the algorithm, e.g. an RSA key for ES256
+	ErrorKeyAlgorithmMismatch = "signing key does not match algorithm"
+	// ErrorBadPEM is returned when no usable key is found in PEM data
+	ErrorBadPEM = "no usable key in PEM data"
+)
+
 // JwtSigner is the main object for simplified JWT operations
 type JwtSigner struct {
 	signer jose.Signer
 }
 
-// TokenFromPSK generates an JWT with signed OrgClaim
+// pskTokenLifetime is how long the tokens from TokenFromPSK are valid
+const pskTokenLifetime = 60 * time.Minute
+
+// TokenFromPSK generates an JWT with signed OrgClaim. Shared secrets are best kept to
+// development, see TokenFromKey.
 func TokenFromPSK(psk string, orgID int) (token string, err error) {
+	return TokenFromKey(jose.HS256, []byte(psk), orgID)
+}
+
+// TokenFromKey generates an JWT with OrgClaim, signed using `alg` and `signingKey`
+// as per JwtSigner.Init, e.g. RS256 with a key from LoadSigningKeyPEM
+func TokenFromKey(alg jose.SignatureAlgorithm, signingKey interface{}, orgID int) (token string, err error) {
 
 	signer := JwtSigner{}
-	err = signer.Init(jose.HS256, []byte(psk))
+	err = signer.Init(alg, signingKey)
 	if err != nil {
 		return "", err
 	}
@@ -24,7 +52,7 @@ func TokenFromPSK(psk string, orgID int) (token string, err error) {
 	// the lifetime should be shorter, but think I'm hitting some timezone issues at the moment
 	orgClaim := OrgClaim{
 		OrgID: orgID,
-		Exp:   time.Now().Add(60 * time.Minute).Unix(),
+		Exp:   time.Now().Add(pskTokenLifetime).Unix(),
 	}
 
 	serialized, err := signer.MarshallSignSerialize(orgClaim)
@@ -35,13 +63,113 @@ func TokenFromPSK(psk string, orgID int) (token string, err error) {
 	return serialized, nil
 }
 
-// Init creates JOSE signer
+// Init creates JOSE signer for `alg`, which must suit the type of `signingKey`:
+// HS256/384/512 take a []byte secret, RS256 and PS256 an *rsa.PrivateKey, and
+// ES256 an *ecdsa.PrivateKey on P-256
 func (s *JwtSigner) Init(alg jose.SignatureAlgorithm, signingKey interface{}) error {
-	var err error
-	s.signer, err = jose.NewSigner(jose.HS256, signingKey)
+	err := checkSigningKey(alg, signingKey)
+	if err != nil {
+		return err
+	}
+	s.signer, err = jose.NewSigner(alg, signingKey)
 	return err
 }
 
+func checkSigningKey(alg jose.SignatureAlgorithm, signingKey interface{}) error {
+	matches := false
+	switch alg {
+	case jose.HS256, jose.HS384, jose.HS512:
+		key, ok := signingKey.([]byte)
+		matches = ok && len(key) > 0
+	case jose.RS256, jose.PS256:
+		_, matches = signingKey.(*rsa.PrivateKey)
+	case jose.ES256:
+		key, ok := signingKey.(*ecdsa.PrivateKey)
+		matches = ok && key.Curve == elliptic.P256()
+	default:
+		return errors.Errorf("%s: %s", ErrorUnsupportedAlgorithm, alg)
+	}
+	if !matches {
+		return errors.Errorf("%s: %T for %s", ErrorKeyAlgorithmMismatch, signingKey, alg)
+	}
+	return nil
+}
+
+// LoadSigningKeyPEM parses the first private key in `data`, whether PKCS #1 or
+// SEC 1 ("RSA PRIVATE KEY"/"EC PRIVATE KEY") or PKCS #8 ("PRIVATE KEY"),
+// returning an *rsa.PrivateKey or *ecdsa.PrivateKey
+func LoadSigningKeyPEM(data []byte) (interface{}, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, errors.New(ErrorBadPEM)
+		}
+		switch block.Type {
+		case "RSA PRIVATE KEY":
+			return x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "EC PRIVATE KEY":
+			return x509.ParseECPrivateKey(block.Bytes)
+		case "PRIVATE KEY":
+			key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			switch key.(type) {
+			case *rsa.PrivateKey, *ecdsa.PrivateKey:
+				return key, nil
+			}
+			return nil, errors.Errorf("%s: unsupported key type %T", ErrorBadPEM, key)
+		}
+	}
+}
+
+// LoadSigningKeyFile is LoadSigningKeyPEM reading from `path`
+func LoadSigningKeyFile(path string) (interface{}, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return LoadSigningKeyPEM(data)
+}
+
+// LoadVerificationKeyPEM parses the first public key in `data` for use with
+// ParseVerify, whether PKIX ("PUBLIC KEY"), PKCS #1 ("RSA PUBLIC KEY") or the
+// key of a certificate, returning an *rsa.PublicKey or *ecdsa.PublicKey
+func LoadVerificationKeyPEM(data []byte) (interface{}, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, errors.New(ErrorBadPEM)
+		}
+		var key interface{}
+		var err error
+		switch block.Type {
+		case "RSA PUBLIC KEY":
+			return x509.ParsePKCS1PublicKey(block.Bytes)
+		case "PUBLIC KEY":
+			key, err = x509.ParsePKIXPublicKey(block.Bytes)
+		case "CERTIFICATE":
+			var cert *x509.Certificate
+			cert, err = x509.ParseCertificate(block.Bytes)
+			if err == nil {
+				key = cert.PublicKey
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch key.(type) {
+		case *rsa.PublicKey, *ecdsa.PublicKey:
+			return key, nil
+		}
+		return nil, errors.Errorf("%s: unsupported key type %T", ErrorBadPEM, key)
+	}
+}
+
 // MarshallSignSerialize returns a compacted serialised JWT from a claims structure
 func (s *JwtSigner) MarshallSignSerialize(in interface{}) (string, error) {
 	claimJSON, err := json.Marshal(in)
@@ -0,0 +1,111 @@
+package deviceserver
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"github.com/square/go-jose"
+	"github.com/stretchr/testify/assert"
+)
+
+func jwtAlg(t *testing.T, token string) string {
+	header, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[0])
+	assert.Nil(t, err)
+	return string(header)
+}
+
+func TestJwtSigner(t *testing.T) {
+	assert := assert.New(t)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(err)
+	secret := []byte("0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef")
+
+	for _, test := range []struct {
+		alg     jose.SignatureAlgorithm
+		signing interface{}
+		verify  interface{}
+	}{
+		{jose.HS256, secret, secret},
+		{jose.HS384, secret, secret},
+		{jose.HS512, secret, secret},
+		{jose.RS256, rsaKey, &rsaKey.PublicKey},
+		{jose.PS256, rsaKey, &rsaKey.PublicKey},
+		{jose.ES256, ecKey, &ecKey.PublicKey},
+	} {
+		token, err := TokenFromKey(test.alg, test.signing, 7)
+		assert.Nil(err, "%s", test.alg)
+		assert.Contains(jwtAlg(t, token), `"alg":"`+string(test.alg)+`"`)
+		claims, err := ParseVerify([]byte(token), test.verify)
+		assert.Nil(err, "%s", test.alg)
+		assert.Contains(string(claims), `"OrgID":7`)
+	}
+
+	var signer JwtSigner
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.Nil(err)
+	for _, mismatch := range []struct {
+		alg jose.SignatureAlgorithm
+		key interface{}
+	}{
+		{jose.HS256, rsaKey},
+		{jose.HS256, []byte{}},
+		{jose.RS256, ecKey},
+		{jose.RS256, &rsaKey.PublicKey},
+		{jose.ES256, rsaKey},
+		{jose.ES256, p384},
+	} {
+		err = signer.Init(mismatch.alg, mismatch.key)
+		assert.True(strings.HasPrefix(err.Error(), ErrorKeyAlgorithmMismatch), "%s %T", mismatch.alg, mismatch.key)
+	}
+	err = signer.Init(jose.ES384, p384)
+	assert.True(strings.HasPrefix(err.Error(), ErrorUnsupportedAlgorithm))
+}
+
+func TestLoadKeysPEM(t *testing.T) {
+	assert := assert.New(t)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(err)
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	assert.Nil(err)
+	sec1, err := x509.MarshalECPrivateKey(ecKey)
+	assert.Nil(err)
+	for _, block := range []*pem.Block{
+		{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)},
+		{Type: "EC PRIVATE KEY", Bytes: sec1},
+		{Type: "PRIVATE KEY", Bytes: pkcs8},
+	} {
+		// anything else in the file, e.g. EC parameters, is skipped
+		data := append(pem.EncodeToMemory(&pem.Block{Type: "EC PARAMETERS", Bytes: []byte{6, 8}}), pem.EncodeToMemory(block)...)
+		key, err := LoadSigningKeyPEM(data)
+		assert.Nil(err, block.Type)
+		assert.NotNil(key, block.Type)
+	}
+
+	pkix, err := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	assert.Nil(err)
+	key, err := LoadVerificationKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pkix}))
+	assert.Nil(err)
+	assert.True(ecKey.PublicKey.Equal(key))
+	key, err = LoadVerificationKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)}))
+	assert.Nil(err)
+	assert.True(rsaKey.PublicKey.Equal(key))
+
+	_, err = LoadSigningKeyPEM([]byte("not PEM"))
+	assert.Equal(ErrorBadPEM, err.Error())
+	_, err = LoadVerificationKeyPEM(nil)
+	assert.Equal(ErrorBadPEM, err.Error())
+}
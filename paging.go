@@ -0,0 +1,141 @@
+package deviceserver
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"sync"
+
+	h "github.com/CreatorKit/go-deviceserver-client/hateoas"
+	"github.com/pkg/errors"
+)
+
+// MaxConcurrentPages limits how many requests PageRange makes at once
+var MaxConcurrentPages = 4
+
+var (
+	// ErrorInvalidPageRange is returned by PageRange for a pageSize below one or a negative first page
+	ErrorInvalidPageRange = "invalid page range"
+)
+
+// ListOptions picks which page of a collection to fetch. Zero values
+// leave the choice to the deviceserver.
+type ListOptions struct {
+	StartIndex int
+	PageSize   int
+}
+
+func (o ListOptions) query() url.Values {
+	q := url.Values{}
+	if o.StartIndex > 0 {
+		q.Set("startIndex", strconv.Itoa(o.StartIndex))
+	}
+	if o.PageSize > 0 {
+		q.Set("pageSize", strconv.Itoa(o.PageSize))
+	}
+	return q
+}
+
+// Pages returns how many pages of `pageSize` items are needed to cover TotalCount
+func (p PageInfo) Pages(pageSize int) int {
+	if pageSize <= 0 {
+		return 0
+	}
+	return (p.TotalCount + pageSize - 1) / pageSize
+}
+
+// ListAccessKeys fetches one page of accesskeys
+func (d *RESTClient) ListAccessKeys(ctx context.Context, opts ListOptions) (*AccessKeys, error) {
+	var keys AccessKeys
+	_, err := d.hclient.GetContext(ctx, "",
+		h.Navigate{"accesskeys"},
+		nil,
+		nil,
+		&keys,
+		h.WithQuery(opts.query()))
+	return &keys, asHTTPError(err)
+}
+
+// ListClients fetches one page of clients
+func (d *RESTClient) ListClients(ctx context.Context, opts ListOptions) (*Clients, error) {
+	var clients Clients
+	_, err := d.hclient.GetContext(ctx, "",
+		h.Navigate{"clients"},
+		nil,
+		nil,
+		&clients,
+		h.WithQuery(opts.query()))
+	return &clients, asHTTPError(err)
+}
+
+// ListSubscriptions fetches one page of the subscriptions on `endpoint`, see GetSubscriptions
+func (d *RESTClient) ListSubscriptions(ctx context.Context, endpoint string, opts ListOptions) (*Subscriptions, error) {
+	var subs Subscriptions
+	_, err := d.hclient.GetContext(ctx, endpoint,
+		h.Navigate{"subscriptions"},
+		nil,
+		nil,
+		&subs,
+		h.WithQuery(opts.query()))
+	return &subs, asHTTPError(err)
+}
+
+// PageRange fetches pages `first` to `last` (inclusive, counting from zero) of `pageSize`
+// items concurrently, typically once TotalCount is known from a first page, e.g.
+//
+//	first, err := d.ListClients(ctx, ListOptions{PageSize: 50})
+//	rest, err := PageRange(ctx, 50, 1, first.PageInfo.Pages(50)-1, d.ListClients)
+//
+// The pages are returned in order. The first error cancels any outstanding requests.
+func PageRange[P any](ctx context.Context, pageSize int, first int, last int, fetch func(context.Context, ListOptions) (*P, error)) ([]*P, error) {
+	if pageSize <= 0 || first < 0 {
+		return nil, errors.Errorf("%s: pageSize %d, first %d", ErrorInvalidPageRange, pageSize, first)
+	}
+	if last < first {
+		return []*P{}, nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	pages := make([]*P, last-first+1)
+	errs := make([]error, len(pages))
+	limit := make(chan struct{}, max(MaxConcurrentPages, 1))
+	var wg sync.WaitGroup
+	for i := range pages {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			select {
+			case limit <- struct{}{}:
+				defer func() { <-limit }()
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+				return
+			}
+			pages[i], errs[i] = fetch(ctx, ListOptions{
+				StartIndex: (first + i) * pageSize,
+				PageSize:   pageSize,
+			})
+			if errs[i] != nil {
+				cancel()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// report the error which caused the cancellation, rather than the cancellation itself
+	var firstErr error
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		if firstErr == nil || (errors.Is(firstErr, context.Canceled) && !errors.Is(err, context.Canceled)) {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return pages, nil
+}
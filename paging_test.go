@@ -0,0 +1,60 @@
+package deviceserver
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListOptions(t *testing.T) {
+	assert.Equal(t, "", ListOptions{}.query().Encode())
+	assert.Equal(t, "pageSize=10&startIndex=20", ListOptions{StartIndex: 20, PageSize: 10}.query().Encode())
+
+	assert.Equal(t, 0, PageInfo{TotalCount: 0}.Pages(10))
+	assert.Equal(t, 1, PageInfo{TotalCount: 10}.Pages(10))
+	assert.Equal(t, 2, PageInfo{TotalCount: 11}.Pages(10))
+	assert.Equal(t, 0, PageInfo{TotalCount: 11}.Pages(0))
+}
+
+func TestPageRange(t *testing.T) {
+	var calls int32
+	fetch := func(ctx context.Context, opts ListOptions) (*Clients, error) {
+		atomic.AddInt32(&calls, 1)
+		return &Clients{PageInfo: PageInfo{StartIndex: opts.StartIndex, ItemsCount: opts.PageSize}}, nil
+	}
+
+	pages, err := PageRange(context.Background(), 5, 2, 6, fetch)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(5), calls)
+	assert.Equal(t, 5, len(pages))
+	for i, page := range pages {
+		assert.Equal(t, (i+2)*5, page.PageInfo.StartIndex)
+		assert.Equal(t, 5, page.PageInfo.ItemsCount)
+	}
+
+	pages, err = PageRange(context.Background(), 5, 2, 1, fetch)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(pages))
+
+	oops := errors.New("oops")
+	_, err = PageRange(context.Background(), 5, 0, 20, func(ctx context.Context, opts ListOptions) (*Clients, error) {
+		if opts.StartIndex == 15 {
+			return nil, oops
+		}
+		return &Clients{}, nil
+	})
+	assert.Equal(t, oops, err)
+
+	// a zero pageSize would fetch the first page over and over
+	calls = 0
+	_, err = PageRange(context.Background(), 0, 0, 3, fetch)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), ErrorInvalidPageRange)
+	_, err = PageRange(context.Background(), 5, -1, 3, fetch)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), ErrorInvalidPageRange)
+	assert.Equal(t, int32(0), calls)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/CreatorKit/go-deviceserver-client/hateoas"
 )
@@ -32,6 +33,9 @@ type AccessKeys struct {
 	Links    hateoas.Links `json:"Links"`
 }
 
+func (k *AccessKeys) PageLinks() hateoas.Links { return k.PageInfo.Links }
+func (k *AccessKeys) PageItems() []AccessKey   { return k.Items }
+
 type SubscriptionAttributes struct {
 	Pmin        string `json:"Pmin,omitempty"`
 	Pmax        string `json:"Pmax,omitempty"`
@@ -61,6 +65,9 @@ type Subscriptions struct {
 	Links    hateoas.Links         `json:"Links"`
 }
 
+func (s *Subscriptions) PageLinks() hateoas.Links         { return s.PageInfo.Links }
+func (s *Subscriptions) PageItems() []SubscriptionRequest { return s.Items }
+
 type OrgClaim struct {
 	OrgID int   `json:"OrgID"`
 	Exp   int64 `json:"exp"`
@@ -71,6 +78,11 @@ type OAuthToken struct {
 	TokenType    string `json:"token_type"`
 	ExpiresIn    int    `json:"expires_in"`
 	RefreshToken string `json:"refresh_token"`
+
+	// Expiry is worked out from ExpiresIn when the token is obtained. The zero value
+	// (serialized as "0001-01-01T00:00:00Z") means the expiry is unknown, so the token
+	// is never refreshed ahead of time. The deviceserver itself doesn't send it.
+	Expiry time.Time `json:"expiry"`
 }
 
 type Error struct {
@@ -90,6 +102,9 @@ type Clients struct {
 	Links    hateoas.Links `json:"Links"`
 }
 
+func (c *Clients) PageLinks() hateoas.Links { return c.PageInfo.Links }
+func (c *Clients) PageItems() []Client      { return c.Items }
+
 type ObjectType struct {
 	ObjectTypeID string        `json:"ObjectTypeID"`
 	Links        hateoas.Links `json:"Links"`
@@ -100,6 +115,9 @@ type ObjectTypes struct {
 	Items    []ObjectType `json:"Items"`
 }
 
+func (o *ObjectTypes) PageLinks() hateoas.Links { return o.PageInfo.Links }
+func (o *ObjectTypes) PageItems() []ObjectType  { return o.Items }
+
 type ObjectInstance map[string]interface{}
 
 func (i ObjectInstance) InstanceID() int {
@@ -0,0 +1,119 @@
+package deviceserver
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FileTokenCache keeps tokens in a single JSON file, keyed by deviceserver URL and
+// access key, so that they can be reused across runs rather than authenticating
+// every time. Tokens are credentials, so the file is only readable by its owner.
+type FileTokenCache struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+// CreateFileTokenCache returns a cache stored at `path`, which needn't exist yet
+func CreateFileTokenCache(path string) *FileTokenCache {
+	return &FileTokenCache{Path: path}
+}
+
+func tokenCacheKey(url string, key string) string {
+	return url + " " + key
+}
+
+// read must be called with c.mu held. A missing file is just an empty cache.
+func (c *FileTokenCache) read() (map[string]OAuthToken, error) {
+	tokens := map[string]OAuthToken{}
+	buf, err := ioutil.ReadFile(c.Path)
+	if os.IsNotExist(err) {
+		return tokens, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(buf, &tokens)
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// write must be called with c.mu held
+func (c *FileTokenCache) write(tokens map[string]OAuthToken) error {
+	buf, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(c.Path)
+	err = os.MkdirAll(dir, 0700)
+	if err != nil {
+		return err
+	}
+	// write then rename, so that a concurrent reader never sees a partial file
+	tmp, err := ioutil.TempFile(dir, filepath.Base(c.Path)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(buf)
+	if err == nil {
+		err = tmp.Chmod(0600)
+	}
+	tmp.Close()
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.Path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// Load returns the token cached for `key` at `url`, or nil if there isn't one
+func (c *FileTokenCache) Load(url string, key string) (*OAuthToken, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	tokens, err := c.read()
+	if err != nil {
+		return nil, err
+	}
+	token, ok := tokens[tokenCacheKey(url, key)]
+	if !ok {
+		return nil, nil
+	}
+	return &token, nil
+}
+
+// Store caches `token` for `key` at `url`, e.g. from RESTClient.OnTokenRefreshed
+func (c *FileTokenCache) Store(url string, key string, token OAuthToken) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	tokens, err := c.read()
+	if err != nil {
+		return err
+	}
+	tokens[tokenCacheKey(url, key)] = token
+	return c.write(tokens)
+}
+
+// Delete forgets the token for `key` at `url`
+func (c *FileTokenCache) Delete(url string, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	tokens, err := c.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := tokens[tokenCacheKey(url, key)]; !ok {
+		return nil
+	}
+	delete(tokens, tokenCacheKey(url, key))
+	return c.write(tokens)
+}
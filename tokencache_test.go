@@ -0,0 +1,83 @@
+package deviceserver
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/CreatorKit/go-deviceserver-client/hateoas"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileTokenCache(t *testing.T) {
+	assert := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "tokens", "cache.json")
+	cache := CreateFileTokenCache(path)
+
+	token, err := cache.Load("http://ds", "key")
+	assert.Nil(err)
+	assert.Nil(token)
+
+	expiry := time.Now().Add(time.Hour).Round(time.Second)
+	assert.Nil(cache.Store("http://ds", "key", OAuthToken{AccessToken: "a", RefreshToken: "r", Expiry: expiry}))
+	assert.Nil(cache.Store("http://ds", "other", OAuthToken{AccessToken: "b"}))
+	info, err := os.Stat(path)
+	assert.Nil(err)
+	assert.Equal(os.FileMode(0600), info.Mode().Perm())
+
+	token, err = CreateFileTokenCache(path).Load("http://ds", "key")
+	assert.Nil(err)
+	assert.Equal("r", token.RefreshToken)
+	assert.True(expiry.Equal(token.Expiry))
+
+	assert.Nil(cache.Delete("http://ds", "key"))
+	token, err = cache.Load("http://ds", "key")
+	assert.Nil(err)
+	assert.Nil(token)
+	token, err = cache.Load("http://ds", "other")
+	assert.Nil(err)
+	assert.Equal("b", token.AccessToken)
+
+	// a token without ExpiresIn comes back with an unknown expiry, so isn't refreshed early
+	assert.True(token.Expiry.IsZero())
+	d, err := Create(hateoas.Create(&hateoas.Client{EntryURL: "http://ds"}))
+	assert.Nil(err)
+	d.SetToken(*token)
+	_, expires := d.Token()
+	assert.True(expires.IsZero())
+}
+
+func TestOnTokenRefreshed(t *testing.T) {
+	assert := assert.New(t)
+
+	ts := createTokenServer(3600)
+	defer ts.Close()
+	d, err := Create(hateoas.Create(&hateoas.Client{EntryURL: ts.URL}))
+	assert.Nil(err)
+
+	cache := CreateFileTokenCache(filepath.Join(t.TempDir(), "cache.json"))
+	d.OnTokenRefreshed(func(token OAuthToken) {
+		assert.Nil(cache.Store(ts.URL, "key", token))
+	})
+
+	// a token from an earlier run is not new, so isn't cached again
+	d.SetCredentials(&AccessKey{Key: "key", Secret: "secret"})
+	d.SetToken(OAuthToken{AccessToken: "old", RefreshToken: "r0", Expiry: time.Now().Add(-time.Minute)})
+	token, err := cache.Load(ts.URL, "key")
+	assert.Nil(err)
+	assert.Nil(token)
+
+	// but having expired it is refreshed before use, and the new one cached
+	_, err = d.GetClients(nil)
+	assert.Nil(err)
+	assert.Equal([]string{"refresh_token"}, ts.grants)
+	assert.Equal([]string{"Bearer t1"}, ts.auth)
+
+	token, err = cache.Load(ts.URL, "key")
+	assert.Nil(err)
+	assert.Equal("t1", token.AccessToken)
+	assert.Equal("r1", token.RefreshToken)
+	assert.WithinDuration(time.Now().Add(time.Hour), token.Expiry, time.Minute)
+}
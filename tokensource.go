@@ -0,0 +1,157 @@
+package deviceserver
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	h "github.com/CreatorKit/go-deviceserver-client/hateoas"
+	"golang.org/x/oauth2"
+)
+
+// TokenSource supplies the tokens a RESTClient authenticates with, see UseTokenSource.
+// Token is called whenever a new token is needed, so implementations should return
+// a fresh one each time rather than caching.
+type TokenSource interface {
+	Token(ctx context.Context) (*OAuthToken, error)
+}
+
+// TokenSourceFunc allows a plain function to be used as a TokenSource
+type TokenSourceFunc func(ctx context.Context) (*OAuthToken, error)
+
+// Token implements TokenSource
+func (f TokenSourceFunc) Token(ctx context.Context) (*OAuthToken, error) {
+	return f(ctx)
+}
+
+// UseTokenSource authenticates with a token from `source`, which is then used instead
+// of Authenticate's AccessKey or RefreshAuth's refresh_token for every token needed
+// later on, whether because the current one is due to expire or was refused
+func (d *RESTClient) UseTokenSource(ctx context.Context, source TokenSource) error {
+	d.mu.Lock()
+	d.source = source
+	d.credentials = nil
+	d.mu.Unlock()
+
+	return d.useToken(ctx, source)
+}
+
+func (d *RESTClient) useToken(ctx context.Context, source TokenSource) error {
+	token, err := source.Token(ctx)
+	if err != nil {
+		return err
+	}
+	d.setToken(*token)
+	return nil
+}
+
+// PasswordTokenSource obtains each token with the access key/secret, via the
+// "authenticate" link of `hclient` (which may be that of the RESTClient using it)
+func PasswordTokenSource(hclient *h.Client, credentials *AccessKey) TokenSource {
+	key := AccessKey{Key: credentials.Key, Secret: credentials.Secret}
+	return TokenSourceFunc(func(ctx context.Context) (*OAuthToken, error) {
+		return passwordGrant(ctx, hclient, &key)
+	})
+}
+
+// refreshTokenSource keeps hold of the refresh_token issued with each new token
+type refreshTokenSource struct {
+	hclient *h.Client
+
+	mu           sync.Mutex
+	refreshToken string
+}
+
+// RefreshTokenSource obtains each token with the refresh_token issued alongside
+// the last one, starting with `refreshToken`
+func RefreshTokenSource(hclient *h.Client, refreshToken string) TokenSource {
+	return &refreshTokenSource{hclient: hclient, refreshToken: refreshToken}
+}
+
+// Token implements TokenSource
+func (s *refreshTokenSource) Token(ctx context.Context) (*OAuthToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, err := refreshGrant(ctx, s.hclient, s.refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if token.RefreshToken != "" {
+		s.refreshToken = token.RefreshToken
+	}
+	return token, nil
+}
+
+// StaticTokenSource always returns the same bearer token, which never expires
+func StaticTokenSource(accessToken string) TokenSource {
+	return TokenSourceFunc(func(ctx context.Context) (*OAuthToken, error) {
+		return &OAuthToken{AccessToken: accessToken, TokenType: "Bearer"}, nil
+	})
+}
+
+// PSKTokenSource mints admin JWTs for `orgID` with TokenFromPSK
+func PSKTokenSource(psk string, orgID int) TokenSource {
+	return TokenSourceFunc(func(ctx context.Context) (*OAuthToken, error) {
+		token, err := TokenFromPSK(psk, orgID)
+		if err != nil {
+			return nil, err
+		}
+		return &OAuthToken{
+			AccessToken: token,
+			TokenType:   "Bearer",
+			ExpiresIn:   int(pskTokenLifetime / time.Second),
+			Expiry:      time.Now().Add(pskTokenLifetime),
+		}, nil
+	})
+}
+
+// FromOAuth2 adapts an oauth2.TokenSource. Note that oauth2 token sources don't take a context.
+func FromOAuth2(source oauth2.TokenSource) TokenSource {
+	return TokenSourceFunc(func(ctx context.Context) (*OAuthToken, error) {
+		token, err := source.Token()
+		if err != nil {
+			return nil, err
+		}
+		t := OAuthToken{
+			AccessToken:  token.AccessToken,
+			TokenType:    token.TokenType,
+			RefreshToken: token.RefreshToken,
+			Expiry:       token.Expiry,
+		}
+		if !token.Expiry.IsZero() {
+			t.ExpiresIn = int(time.Until(token.Expiry) / time.Second)
+		}
+		return &t, nil
+	})
+}
+
+// oauth2TokenSource binds a TokenSource to a context, as oauth2.TokenSource has none
+type oauth2TokenSource struct {
+	ctx    context.Context
+	source TokenSource
+}
+
+// ToOAuth2 adapts a TokenSource for use with golang.org/x/oauth2, e.g. wrapped in
+// oauth2.ReuseTokenSource. Every token is obtained using `ctx`.
+func ToOAuth2(ctx context.Context, source TokenSource) oauth2.TokenSource {
+	return &oauth2TokenSource{ctx: ctx, source: source}
+}
+
+// Token implements oauth2.TokenSource
+func (s *oauth2TokenSource) Token() (*oauth2.Token, error) {
+	token, err := s.source.Token(s.ctx)
+	if err != nil {
+		return nil, err
+	}
+	expiry := token.Expiry
+	if expiry.IsZero() && token.ExpiresIn > 0 {
+		expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	return &oauth2.Token{
+		AccessToken:  token.AccessToken,
+		TokenType:    token.TokenType,
+		RefreshToken: token.RefreshToken,
+		Expiry:       expiry,
+	}, nil
+}
@@ -0,0 +1,73 @@
+package deviceserver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/CreatorKit/go-deviceserver-client/hateoas"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+func TestTokenSources(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	ts := createTokenServer(3600)
+	defer ts.Close()
+	d, err := Create(hateoas.Create(&hateoas.Client{EntryURL: ts.URL}))
+	assert.Nil(err)
+
+	// the source is used again whenever a token is refused
+	assert.Nil(d.UseTokenSource(ctx, PasswordTokenSource(d.HATEOAS(), &AccessKey{Key: "key", Secret: "secret"})))
+	ts.revoked["t1"] = true
+	_, err = d.GetClients(nil)
+	assert.Nil(err)
+	assert.Equal([]string{"password", "password"}, ts.grants)
+	assert.Equal([]string{"Bearer t1", "Bearer t2"}, ts.auth)
+	token, expires := d.Token()
+	assert.Equal("t2", token.AccessToken)
+	assert.WithinDuration(time.Now().Add(time.Hour), expires, time.Minute)
+
+	refresh := RefreshTokenSource(d.HATEOAS(), "r2")
+	token2, err := refresh.Token(ctx)
+	assert.Nil(err)
+	assert.Equal("r3", token2.RefreshToken)
+	assert.Equal("r3", refresh.(*refreshTokenSource).refreshToken)
+
+	ts.auth = nil
+	assert.Nil(d.UseTokenSource(ctx, StaticTokenSource("static")))
+	_, err = d.GetClients(nil)
+	assert.Nil(err)
+	assert.Equal([]string{"Bearer static"}, ts.auth)
+	_, expires = d.Token()
+	assert.True(expires.IsZero())
+
+	psk, err := PSKTokenSource("secret", 3).Token(ctx)
+	assert.Nil(err)
+	claims, err := ParseVerify([]byte(psk.AccessToken), []byte("secret"))
+	assert.Nil(err)
+	assert.Contains(string(claims), `"OrgID":3`)
+	assert.WithinDuration(time.Now().Add(time.Hour), psk.Expiry, time.Minute)
+}
+
+func TestOAuth2TokenSources(t *testing.T) {
+	assert := assert.New(t)
+
+	token, err := ToOAuth2(context.Background(), TokenSourceFunc(func(ctx context.Context) (*OAuthToken, error) {
+		return &OAuthToken{AccessToken: "a", TokenType: "Bearer", ExpiresIn: 60, RefreshToken: "r"}, nil
+	})).Token()
+	assert.Nil(err)
+	assert.Equal("a", token.AccessToken)
+	assert.Equal("r", token.RefreshToken)
+	assert.WithinDuration(time.Now().Add(time.Minute), token.Expiry, time.Second)
+	assert.True(token.Valid())
+
+	expiry := time.Now().Add(time.Hour)
+	ours, err := FromOAuth2(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "b", Expiry: expiry})).Token(context.Background())
+	assert.Nil(err)
+	assert.Equal("b", ours.AccessToken)
+	assert.Equal(expiry, ours.Expiry)
+	assert.InDelta(3600, ours.ExpiresIn, 2)
+}